@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	pp "github.com/mpmcintyre/process-party/internal"
+)
+
+// Commands controlling processes, called with the arguments following the selected processes
+var controlCommands = map[string]func(context *pp.ExecutionContext, args []string) error{
+	"start": func(context *pp.ExecutionContext, args []string) error {
+		return context.StartProcess()
+	},
+	"stop": func(context *pp.ExecutionContext, args []string) error {
+		return context.StopProcess()
+	},
+	"kill": func(context *pp.ExecutionContext, args []string) error {
+		return context.KillProcess()
+	},
+	"restart": func(context *pp.ExecutionContext, args []string) error {
+		return context.RestartProcess()
+	},
+	"trigger": func(context *pp.ExecutionContext, args []string) error {
+		return context.TriggerProcess()
+	},
+	"signal": func(context *pp.ExecutionContext, args []string) error {
+		if len(args) == 0 {
+			return errors.New("no signal provided")
+		}
+		sig, err := pp.ParseSignal(args[0])
+		if err != nil {
+			return err
+		}
+		return context.Signal(sig)
+	},
+}
+
+// Runs the control command on every process selected by the name, prefix, index or glob pattern, e.g. "restart api-*"
+func runControlCommand(fields []string, contexts []*pp.ExecutionContext) {
+	if len(fields) < 2 {
+		color.HiBlack("No process provided - %s <name|prefix|index|pattern>", fields[0])
+		return
+	}
+	matched, err := pp.MatchContexts(fields[1], contexts)
+	if err != nil {
+		fmt.Fprintln(color.Output, err.Error())
+		return
+	}
+	for _, context := range matched {
+		err := controlCommands[fields[0]](context, fields[2:])
+		if err != nil {
+			fmt.Fprintf(color.Output, "Could not %s %s: %s\n", fields[0], displayName(context), err.Error())
+		}
+	}
+}
+
+// Returns the name of the process, inline commands only have a prefix
+func displayName(context *pp.ExecutionContext) string {
+	if context.Process.Name != "" {
+		return context.Process.Name
+	}
+	return context.Process.Prefix
+}
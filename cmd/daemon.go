@@ -0,0 +1,424 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	pp "github.com/mpmcintyre/process-party/internal"
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+)
+
+const (
+	daemonEnv          = "PROCESS_PARTY_DAEMON" // Set for the party started in the background by up -d
+	daemonStartTimeout = 10 * time.Second
+	downGracePeriod    = 5 * time.Second // Added to the stop timeouts of the processes when waiting for the party to exit
+)
+
+var detach *bool
+var followLogs *bool
+var logLines *int
+
+// Sends requests to the party listening on the socket
+type socketClient struct {
+	client *http.Client
+}
+
+// Returns true if this is the party started in the background by up -d
+func isDaemon() bool {
+	return os.Getenv(daemonEnv) != ""
+}
+
+// Returns the file the output of a detached party is written to
+func daemonLogPath() string {
+	return strings.TrimSuffix(*socketPath, ".sock") + ".log"
+}
+
+func newSocketClient() *socketClient {
+	return &socketClient{client: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", *socketPath)
+		},
+	}}}
+}
+
+// Sends the request to the party and returns the response, failing on error responses.
+// Conflicts are returned as control commands report the result of every process
+func (c *socketClient) request(method string, path string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequest(method, "http://localhost"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("process party is not running (no party listening on %s)", *socketPath)
+	}
+	if response.StatusCode >= 400 && response.StatusCode != http.StatusConflict {
+		return nil, responseError(response)
+	}
+	return response, nil
+}
+
+// Returns the error sent by the party and closes the response
+func responseError(response *http.Response) error {
+	defer response.Body.Close()
+	apiError := map[string]string{}
+	if json.NewDecoder(response.Body).Decode(&apiError) != nil || apiError["error"] == "" {
+		return errors.New(response.Status)
+	}
+	return errors.New(apiError["error"])
+}
+
+// Sends the request to the party and decodes the JSON response into the value
+func (c *socketClient) getJson(method string, path string, value any) error {
+	response, err := c.request(method, path, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// Prints the lines of output, prefixed by their process when showing multiple processes
+func printLogEntry(entry pp.LogEntry, prefixed bool) {
+	text := entry.Text
+	if entry.Error {
+		text = color.RedString(text)
+	}
+	if prefixed {
+		text = color.HiBlackString("[%s] ", entry.Process) + text
+	}
+	fmt.Println(text)
+}
+
+// Prints the recent output of the process, or of every process if empty. Followed logs are printed until the party ends
+func printLogs(client *socketClient, process string, lines int, follow bool) error {
+	path := "/logs"
+	if process != "" {
+		path = "/processes/" + url.PathEscape(process) + "/logs"
+	}
+	path += fmt.Sprintf("?lines=%d&follow=%t", lines, follow)
+	response, err := client.request("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if !follow {
+		entries := []pp.LogEntry{}
+		if err := json.NewDecoder(response.Body).Decode(&entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			printLogEntry(entry, process == "")
+		}
+		return nil
+	}
+	decoder := json.NewDecoder(response.Body)
+	for {
+		entry := pp.LogEntry{}
+		if err := decoder.Decode(&entry); err != nil {
+			color.HiBlack("Process party has ended")
+			return nil
+		}
+		printLogEntry(entry, process == "")
+	}
+}
+
+// Starts the party in the background and waits for its socket
+func startDaemon() error {
+	if _, err := newSocketClient().request("GET", "/processes", nil); err == nil {
+		return fmt.Errorf("process party is already running on %s", *socketPath)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := os.Create(daemonLogPath())
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	daemon := exec.Command(executable, os.Args[1:]...)
+	daemon.Env = append(os.Environ(), daemonEnv+"=1")
+	daemon.Stdout = logFile
+	daemon.Stderr = logFile
+	detachProcess(daemon)
+	if err := daemon.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		daemon.Wait()
+		close(exited)
+	}()
+
+	// Wait for the party to listen on the socket, or to fail starting
+	timeout := time.After(daemonStartTimeout)
+	for {
+		select {
+		case <-exited:
+			return fmt.Errorf("process party exited while starting, see %s", daemonLogPath())
+		case <-timeout:
+			return fmt.Errorf("process party did not start within %s, see %s", daemonStartTimeout, daemonLogPath())
+		case <-time.After(100 * time.Millisecond):
+		}
+		if _, err := newSocketClient().request("GET", "/processes", nil); err == nil {
+			color.HiBlack("Process party is running in the background (PID %d), output is written to %s", daemon.Process.Pid, daemonLogPath())
+			color.HiBlack("Use \"process-party ps\" to list the processes and \"process-party down\" to stop them")
+			return nil
+		}
+	}
+}
+
+// upCmd runs a party that can be controlled by the other commands, optionally in the background
+var upCmd = &cobra.Command{
+	Use:          "up ./path/to/config.yml",
+	Short:        "Start a party that can be controlled with ps, logs, restart, attach and down",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *detach && !isDaemon() {
+			return startDaemon()
+		}
+		return runParty(args, true)
+	},
+}
+
+var psCmd = &cobra.Command{
+	Use:          "ps",
+	Short:        "List the processes of the running party",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		processes := []pp.ProcessInfo{}
+		if err := newSocketClient().getJson("GET", "/processes", &processes); err != nil {
+			return err
+		}
+		headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+		columnFmt := color.New(color.FgYellow).SprintfFunc()
+		tbl := table.New("Index", "Name", "Prefix", "Command", "Status", "PID", "Restarts", "Exit code")
+		tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+		for _, process := range processes {
+			pid, exitCode := "", ""
+			if process.Pid != 0 {
+				pid = fmt.Sprint(process.Pid)
+			}
+			if process.ExitCode != nil {
+				exitCode = fmt.Sprint(*process.ExitCode)
+			}
+			tbl.AddRow(process.Index, process.Name, process.Prefix, process.Command, process.Status, pid, process.Restarts, exitCode)
+		}
+		tbl.Print()
+		return nil
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:          "logs [name|prefix|index]",
+	Short:        "Show the recent output of a process, or of every process",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		process := ""
+		if len(args) != 0 {
+			process = args[0]
+		}
+		return printLogs(newSocketClient(), process, *logLines, *followLogs)
+	},
+}
+
+// Sends the input to the process, or to every running process for all
+func writeRemoteInput(client *socketClient, target string, text string) error {
+	targets := []string{target}
+	if target == "all" {
+		processes := []pp.ProcessInfo{}
+		if err := client.getJson("GET", "/processes", &processes); err != nil {
+			return err
+		}
+		targets = []string{}
+		for _, process := range processes {
+			if process.Running {
+				targets = append(targets, fmt.Sprint(process.Index))
+			}
+		}
+	}
+	body, err := json.Marshal(pp.StdinRequest{Input: text})
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		response, err := client.request("POST", "/processes/"+url.PathEscape(target)+"/stdin", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if response.StatusCode == http.StatusConflict {
+			return responseError(response)
+		}
+		response.Body.Close()
+	}
+	return nil
+}
+
+// Sends every line of the standard input to the process, or as <process>:<input> lines if empty
+func attachInput(client *socketClient, process string) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			target, text := process, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if target == "" {
+				input := pp.ParseInput(line, false, func(string) bool { return false })
+				target, text = input.Target, input.Text
+			}
+			if target == "" {
+				color.HiBlack("Send input using <process>:<input>")
+			} else if err := writeRemoteInput(client, target, text); err != nil {
+				fmt.Printf("Could not write to %s: %s\n", target, err.Error())
+			}
+		}
+		if readErr != nil {
+			return
+		}
+	}
+}
+
+var attachCmd = &cobra.Command{
+	Use:          "attach [name|prefix|index]",
+	Short:        "Follow the output of the running party and send it input, press ctrl+c to detach",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newSocketClient()
+		process := ""
+		if len(args) != 0 {
+			process = args[0]
+			color.HiBlack("Attached to %s - every line is sent to the process, press ctrl+c to detach", process)
+		} else {
+			color.HiBlack("Attached to process party - send input using <process>:<input>, press ctrl+c to detach")
+		}
+		// Detaching leaves the party running
+		detached := make(chan os.Signal, 1)
+		signal.Notify(detached, os.Interrupt)
+		go attachInput(client, process)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- printLogs(client, process, 20, true)
+		}()
+		select {
+		case err := <-done:
+			return err
+		case <-detached:
+			color.HiBlack("Detached, the party keeps running")
+			return nil
+		}
+	},
+}
+
+var downCmd = &cobra.Command{
+	Use:          "down",
+	Short:        "Gracefully stop every process of the running party and wait for it to exit",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := newSocketClient()
+		processes := []pp.ProcessInfo{}
+		if err := client.getJson("GET", "/processes", &processes); err != nil {
+			return err
+		}
+		// Processes are stopped one at a time, each is killed once its stop timeout passed
+		timeout := downGracePeriod
+		for _, process := range processes {
+			if process.Running {
+				timeout += time.Duration(process.StopTimeout) * time.Second
+			}
+		}
+
+		response, err := client.request("POST", "/shutdown", nil)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		color.HiBlack("Stopping process party")
+		// The socket closes once the party has exited
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+			if err := client.getJson("GET", "/processes", &processes); err != nil {
+				color.HiBlack("Process party has stopped")
+				return nil
+			}
+		}
+		running := []string{}
+		for _, process := range processes {
+			if process.Running && process.Name != "" {
+				running = append(running, process.Name)
+			} else if process.Running {
+				running = append(running, process.Prefix)
+			}
+		}
+		if len(running) == 0 {
+			return fmt.Errorf("process party did not exit within %s", timeout)
+		}
+		return fmt.Errorf("process party did not exit within %s, still running: %s", timeout, strings.Join(running, ", "))
+	},
+}
+
+// Creates a command running the control command on the processes of the running party
+func createControlCmd(command string, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:          command + " <name|prefix|index|pattern>",
+		Short:        short,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results := []pp.ControlResult{}
+			err := newSocketClient().getJson("POST", "/processes/"+url.PathEscape(args[0])+"/"+command, &results)
+			if err != nil {
+				return err
+			}
+			failed := false
+			for _, result := range results {
+				if result.Error != "" {
+					fmt.Printf("Could not %s %s: %s\n", command, result.Name, result.Error)
+					failed = true
+				}
+			}
+			if failed {
+				return fmt.Errorf("could not %s every process", command)
+			}
+			return nil
+		},
+	}
+}
+
+func init() {
+	detach = upCmd.Flags().BoolP("detach", "d", false, "Run the party in the background")
+	logLines = logsCmd.Flags().IntP("lines", "n", 100, "Number of recent lines to show (0 for all kept lines)")
+	followLogs = logsCmd.Flags().BoolP("follow", "f", false, "Keep showing new output until the party ends")
+	rootCmd.AddCommand(upCmd, psCmd, logsCmd, attachCmd, downCmd,
+		createControlCmd("start", "Start a stopped process of the running party"),
+		createControlCmd("stop", "Gracefully stop a process of the running party"),
+		createControlCmd("kill", "Immediately kill a process of the running party"),
+		createControlCmd("restart", "Restart a process of the running party"),
+		createControlCmd("trigger", "Trigger a process of the running party"),
+	)
+}
@@ -0,0 +1,11 @@
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Runs the command in a new session so it keeps running after the terminal is closed
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
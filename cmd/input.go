@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	pp "github.com/mpmcintyre/process-party/internal"
+	"github.com/rodaine/table"
+)
+
+// Commands handled by the input monitor besides the control commands
+var inputCommands = map[string]bool{
+	"status": true,
+	"s":      true,
+	"help":   true,
+	"exit":   true,
+	"focus":  true,
+	"eof":    true,
+}
+
+// Returns true if the word is a command rather than input for a process
+func isInputCommand(word string) bool {
+	return inputCommands[word] || controlCommands[word] != nil
+}
+
+// Writes the text to the context if it is running, quietly skipping processes that are not running
+func writeInput(context *pp.ExecutionContext, text string, quiet bool) {
+	if context.GetStatus().IsRunning() && context.Write(text) == nil {
+		return
+	}
+	if !quiet {
+		fmt.Fprintf(color.Output, "The %s command is %s, cannot write to process\n", displayName(context), context.GetStatusAsStr())
+	}
+}
+
+// Reads commands and input for the processes from the standard input until it is closed or the party exits
+func monitorInput(runContexts []*pp.ExecutionContext) {
+	reader := bufio.NewReader(os.Stdin)
+	// Every line is written to the focused process until the focus hotkey is entered
+	var focused *pp.ExecutionContext
+
+input_loop:
+	for {
+		line, readErr := reader.ReadString('\n')
+
+		input := pp.ParseInput(line, focused != nil, isInputCommand)
+		switch {
+		case line == "":
+
+		case input.Unfocus:
+			color.HiBlack("Stopped focusing %s", displayName(focused))
+			focused = nil
+
+		case input.Command == "" && input.Target == "":
+			if focused != nil {
+				writeInput(focused, input.Text, false)
+			}
+
+		case input.Command == "":
+			matched, err := pp.MatchContexts(input.Target, runContexts)
+			if err != nil {
+				fmt.Fprintln(color.Output, err.Error())
+				break
+			}
+			for _, context := range matched {
+				writeInput(context, input.Text, input.Target == "all")
+			}
+
+		case controlCommands[input.Command] != nil:
+			runControlCommand(append([]string{input.Command}, input.Args...), runContexts)
+
+		case input.Command == "status" || input.Command == "s":
+			// Print runcontexts status
+			if len(runContexts) > 0 {
+				fmt.Fprintln(color.Output)
+				// Print status of every command
+				headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+				columnFmt := color.New(color.FgYellow).SprintfFunc()
+				tbl := table.New("Index", "Name", "Prefix", "Command", "Status")
+				tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt).WithWriter(color.Output)
+				for index, context := range runContexts {
+					tbl.AddRow(index, context.Process.Name, context.Process.Prefix, context.Process.Command, context.GetStatusAsStr())
+				}
+				tbl.Print()
+				fmt.Fprintln(color.Output)
+			}
+
+		case input.Command == "help":
+			color.HiBlack(`The input allows you to view the status of all
+commands with the "status" command, pipe input to a
+specific command using <command name|command prefix>:<input>
+e.g. "cmd:echo hello", or pipe input to all commands using 
+"all:<input>". Gracefully shutdown all processes using ctrl+c 
+or input "exit" into the command line.
+
+Control processes using start, stop, kill, restart or trigger
+followed by the process name, prefix, index, a glob pattern or
+"all", e.g. "restart api" or "stop worker-*". Send a signal
+using "signal <process> <signal>", e.g. "signal api SIGHUP".
+
+Send every line to a single process using "focus <process>",
+and stop focusing using ctrl+] followed by enter. While focused,
+start lines with \ to run commands, e.g. "\status", or with \\
+to send a line starting with \. Close the input of a process
+using "eof <process>", or ctrl+d while it is focused.`)
+
+		case input.Command == "focus" || input.Command == "eof":
+			if len(input.Args) == 0 {
+				color.HiBlack("No process provided - %s <name|prefix|index>", input.Command)
+				break
+			}
+			matched, err := pp.MatchContexts(input.Args[0], runContexts)
+			if err != nil {
+				fmt.Fprintln(color.Output, err.Error())
+				break
+			}
+			if len(matched) > 1 {
+				fmt.Fprintf(color.Output, "%s matches %d processes, %s needs a single process\n", input.Args[0], len(matched), input.Command)
+				break
+			}
+			if input.Command == "eof" {
+				if err := matched[0].CloseInput(); err != nil {
+					fmt.Fprintf(color.Output, "Could not close the input of %s: %s\n", displayName(matched[0]), err.Error())
+				}
+				break
+			}
+			focused = matched[0]
+			color.HiBlack("Focusing %s - every line is sent to the process, press ctrl+] and enter to stop focusing", displayName(focused))
+
+		case input.Command == "exit":
+			color.HiBlack("Exiting all")
+			pp.Shutdown(runContexts)
+			break input_loop
+
+		default:
+			fmt.Fprintf(color.Output, "Unknown command %s, use \"help\" to list the commands\n", input.Command)
+		}
+
+		if readErr != nil {
+			// Ctrl+D closes the input of the focused process
+			if focused != nil {
+				if err := focused.CloseInput(); err != nil {
+					fmt.Fprintf(color.Output, "Could not close the input of %s: %s\n", displayName(focused), err.Error())
+				}
+				color.HiBlack("Stopped focusing %s", displayName(focused))
+				focused = nil
+				continue
+			}
+			// Closed input (e.g. piped from a file or /dev/null) can't be read again, terminals keep reading after ctrl+d
+			if !pp.IsTerminal(os.Stdin) {
+				break input_loop
+			}
+		}
+	}
+}
@@ -4,21 +4,27 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/fatih/color"
 	pp "github.com/mpmcintyre/process-party/internal"
-	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
 )
 
 var execCommands []string
 var generateConfig *bool
+var shellMode *bool
+var successPolicy *string
+var tuiMode *bool
+var apiAddress *string
+var socketPath *string
+var outputFormat *string
 
 func createSectionHeading(length int, character string, title string) string {
 	wraplength := (length - len(title)) / 2
@@ -43,199 +49,224 @@ using ctrl+c or input "exit" into the command line.
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	RunE: func(cmd *cobra.Command, args []string) error {
+		return runParty(args, false)
+	},
+}
+
+// Runs the processes of the config in the arguments, or the config found in the current directory.
+// Serving the socket allows the party to be controlled by the other commands
+func runParty(args []string, serveSocket bool) error {
+	// The JSON output is the only output on the standard output, messages from process party are written to the standard error
+	switch *outputFormat {
+	case "text":
+	case "json":
+		if *tuiMode {
+			return errors.New("the tui cannot be used with the json output")
+		}
+		color.Output = os.Stderr
+	default:
+		return fmt.Errorf("unknown output %s, use text or json", *outputFormat)
+	}
 
-		// Print ascii art
-		color.HiGreen("   ___                             ___           __      \n  / _ \\_______  _______ ___ ___   / _ \\___ _____/ /___ __\n / ___/ __/ _ \\/ __/ -_|_-<(_-<  / ___/ _ `/ __/ __/ // /\n/_/  /_/  \\___/\\__/\\__/___/___/ /_/   \\_,_/_/  \\__/\\_, / \n                                                  /___/  ")
+	// Print ascii art
+	color.HiGreen("   ___                             ___           __      \n  / _ \\_______  _______ ___ ___   / _ \\___ _____/ /___ __\n / ___/ __/ _ \\/ __/ -_|_-<(_-<  / ___/ _ `/ __/ __/ // /\n/_/  /_/  \\___/\\__/\\__/___/___/ /_/   \\_,_/_/  \\__/\\_, / \n                                                  /___/  ")
 
-		sectionHeadingLength := 80
-		headingChar := "-"
-		// Create the configuration to store settings and process configurations
-		config := pp.CreateConfig()
+	sectionHeadingLength := 80
+	headingChar := "-"
+	// Create the configuration to store settings and process configurations
+	config := pp.CreateConfig()
 
-		fmt.Println()
-		color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Parsing inputs"))
+	fmt.Fprintln(color.Output)
+	color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Parsing inputs"))
 
-		// If the user wishes to generate an empty config, asist in generating a config
-		if *generateConfig {
-			path := "process-party.yml"
-			if len(args) != 0 {
-				path = args[0]
-			}
-			err := config.GenerateExampleConfig(path)
+	// If the user wishes to generate an empty config, asist in generating a config
+	if *generateConfig {
+		path := "process-party.yml"
+		if len(args) != 0 {
+			path = args[0]
+		}
+		err := config.GenerateExampleConfig(path)
+		return err
+	}
+
+	// Parse the input file if the user passes in an argument
+	if len(args) != 0 {
+		// Parse the input file path
+		err := config.ParseFile(args[0], false)
+		if err != nil {
 			return err
 		}
+	} else {
+		// Check if there is a process-party file in parent dir
 
-		// Parse the input file if the user passes in an argument
-		if len(args) != 0 {
-			// Parse the input file path
-			err := config.ParseFile(args[0], false)
+		targetFile, err := config.ScanDir(".")
+		if err != nil {
+			return err
+		}
+		if targetFile != "" {
+			err := config.ParseFile(targetFile, false)
 			if err != nil {
 				return err
 			}
-		} else {
-			// Check if there is a process-party file in parent dir
+		}
+	}
 
-			targetFile, err := config.ScanDir(".")
-			if err != nil {
-				return err
-			}
-			if targetFile != "" {
-				err := config.ParseFile(targetFile, false)
-				if err != nil {
-					return err
-				}
-			}
+	// Parse the inline commands (-e or --execute flag)
+	if *shellMode {
+		config.Shell = true
+	}
+	for _, cmd := range execCommands {
+		err := config.ParseInlineCmd(cmd)
+		if err != nil {
+			return err
 		}
+	}
 
-		// Parse the inline commands (-e or --execute flag)
-		for _, cmd := range execCommands {
-			err := config.ParseInlineCmd(cmd)
-			if err != nil {
-				return err
-			}
+	color.HiBlack("Input is active - std in to commands using [all] or specific command using [<cmd prefix>]")
+	color.HiBlack("Get the status using \"status\" or \"s\", or quit the party using \"exit\" or ctrl+c")
+	fmt.Fprintln(color.Output)
+	color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Linking triggers"))
+	fmt.Fprintln(color.Output)
+
+	// Create the waitgroup
+	var wg sync.WaitGroup
+
+	// Generate the contexts for all processes in the config
+	runContexts := config.GenerateRunTaskContexts(&wg)
+	if *outputFormat == "json" {
+		for _, context := range runContexts {
+			context.SetJsonOutput(os.Stdout)
 		}
+	}
+	// Link contexts with their triggers
+	err := pp.LinkProcessTriggers(runContexts)
+	if err != nil {
+		return err
+	}
+	// Link contexts with the processes they depend on
+	err = pp.LinkProcessDependencies(runContexts)
+	if err != nil {
+		return err
+	}
+	// Choose which processes decide the exit code
+	if *successPolicy != "" {
+		config.Success = pp.SuccessPolicy(*successPolicy)
+	}
+	success := config.GetSuccessPolicy()
+	err = success.Validate(runContexts)
+	if err != nil {
+		return err
+	}
 
-		color.HiBlack("Input is active - std in to commands using [all] or specific command using [<cmd prefix>]")
-		color.HiBlack("Get the status using \"status\" or \"s\", or quit the party using \"exit\" or ctrl+c")
-		fmt.Println()
-		color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Linking triggers"))
-		fmt.Println()
+	// Show the output in the TUI, otherwise start an input stream monitor. Detached parties have no input
+	var tui *pp.Tui
+	if isDaemon() {
+		if *tuiMode {
+			return errors.New("the tui cannot be used by a detached party")
+		}
+	} else if *tuiMode {
+		// The TUI draws its own colours
+		color.NoColor = true
+		tui = pp.CreateTui(runContexts)
+	} else {
+		go monitorInput(runContexts)
+	}
 
-		// Create the waitgroup
-		var wg sync.WaitGroup
+	fmt.Fprintln(color.Output)
+	color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Launching"))
+	fmt.Fprintln(color.Output)
+	if len(runContexts) == 0 {
+		return errors.New("no processes to run")
+	}
 
-		// Generate the contexts for all processes in the config
-		runContexts := config.GenerateRunTaskContexts(&wg)
-		// Link contexts with their triggers
-		err := pp.LinkProcessTriggers(runContexts)
+	// Serve the control API if an address is configured, and the socket used by the other commands
+	servers := []*pp.ApiServer{}
+	closeServers := func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+	defer closeServers()
+	if *apiAddress != "" {
+		config.Api = *apiAddress
+	}
+	if config.Api != "" {
+		api, err := pp.StartApi(config.Api, runContexts)
 		if err != nil {
 			return err
 		}
+		servers = append(servers, api)
+		color.HiBlack("Control API listening on http://%s", api.Address())
+	}
+	if serveSocket {
+		socket, err := pp.StartSocket(*socketPath, runContexts)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, socket)
+		color.HiBlack("Control socket listening on %s", *socketPath)
+	}
 
-		// Start an input stream monitor
+	if tui != nil {
+		err := tui.Start()
+		if err != nil {
+			return err
+		}
+		// Quitting the TUI gracefully shuts down the party and quitting again kills it
 		go func() {
-			reader := bufio.NewReader(os.Stdin)
-
-		input_loop:
-			for {
-				text, _ := reader.ReadString('\n')
-				text = strings.TrimSpace(text) // Remove leading/trailing whitespace including newlines
-				s := strings.Split(text, ":")  // Split by ":"
-				if len(s) < 1 {
-					continue
-				}
-				target := ""
-				if len(s) == 1 {
-					target = text
-				} else {
-					target = s[0]
-				}
-
-				// Apply shorthands
-				if target == "s" {
-					target = "status"
-				}
-
-				switch target {
-				case "all":
-					if len(s) < 2 {
-						color.HiBlack("No input provided")
-						continue
-					}
-					input := s[1:]
-					for _, context := range runContexts {
-						if context.Status == pp.ProcessStatusRunning {
-							context.Write(strings.Join(input, ""))
-						}
-					}
-
-					// Broadcast to all processes
-				case "status":
-					// Print runcontexts status
-					if len(runContexts) > 0 {
-						fmt.Println()
-						// Print status of every command
-						headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
-						columnFmt := color.New(color.FgYellow).SprintfFunc()
-						tbl := table.New("Index", "Name", "Prefix", "Command", "Status")
-						tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
-						for index, context := range runContexts {
-							tbl.AddRow(index, context.Process.Name, context.Process.Prefix, context.Process.Command, context.GetStatusAsStr())
-						}
-						tbl.Print()
-						fmt.Println()
-					}
-
-				case "help":
-					color.HiBlack(`The input allows you to view the status of all
-commands with the "status" command, pipe input to a
-specific command using <command name|command prefix>:<input>
-e.g. "cmd:echo hello", or pipe input to all commands using 
-"all:<input>". Gracefully shutdown all processes using ctrl+c 
-or input "exit" into the command line.`)
-
-				case "exit":
-					color.HiBlack("Exiting all")
-					for _, context := range runContexts {
-						context.BuzzkillProcess()
-					}
-					break input_loop
-
-				default:
-					if len(s) < 2 {
-						color.HiBlack("No input provided")
-						continue
-					}
-					found := false
-					input := s[1:]
-					for _, context := range runContexts {
-						if context.Process.Name == target || context.Process.Prefix == target {
-							found = true
-							if context.Status == pp.ProcessStatusRunning {
-								context.Write(strings.Join(input, ""))
-							} else {
-								fmt.Printf("The %s command is %s, cannot write to process\n", target, context.GetStatusAsStr())
-							}
-						}
-					}
-					if !found {
-						fmt.Printf("%s not found", target)
-					}
-				}
+			<-tui.Quit()
+			go pp.Shutdown(runContexts)
+			<-tui.Kill()
+			for _, context := range runContexts {
+				context.Kill()
 			}
 		}()
+	}
 
-		fmt.Println()
-		color.HiBlack(createSectionHeading(sectionHeadingLength, headingChar, "Launching"))
-		fmt.Println()
-		if len(runContexts) == 0 {
-			return errors.New("no processes to run")
-		}
+	// Start the tasks
+	for _, context := range runContexts {
+		context.Start()
+	}
 
-		// Start the tasks
+	// Listen to signals, the first gracefully shuts down the party and the second kills it
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc,
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGTERM)
+	go func() {
+		sig := <-sigc
+		color.HiBlack("Recieved %s, shutting down all processes (repeat to force)", sig)
+		go pp.Shutdown(runContexts)
+		sig = <-sigc
+		color.HiBlack("Recieved %s, killing all processes", sig)
 		for _, context := range runContexts {
-			context.Start()
+			context.Kill()
 		}
+		if tui != nil {
+			tui.Stop()
+		}
+		closeServers()
+		os.Exit(1)
+	}()
 
-		// // Listen to signals
-		// sigc := make(chan os.Signal, 1)
-		// signal.Notify(sigc,
-		// 	syscall.SIGHUP,
-		// 	syscall.SIGINT,
-		// 	syscall.SIGTERM,
-		// 	syscall.SIGQUIT)
-		// go func() {
-		// 	<-sigc
-		// 	color.HiBlack("Recieved exit signal, exiting all")
-		// 	for _, context := range runContexts {
-		// 		context.BuzzkillProcess()
-		// 	}
-		// }()
-
-		wg.Wait()
-
-		return nil
-	},
+	wg.Wait()
+	if tui != nil {
+		// Keep the output visible until the TUI is quit
+		tui.Message("All processes have ended - press q to exit")
+		<-tui.Quit()
+		tui.Stop()
+	}
+
+	// Exit with the code of the processes chosen by the success policy
+	exitCode := success.ExitCode(runContexts)
+	if exitCode != 0 {
+		color.HiBlack("Exiting with code %d (success policy: %s)", exitCode, success)
+		closeServers()
+		os.Exit(exitCode)
+	}
+
+	return nil
 }
 
 func Execute() {
@@ -248,4 +279,12 @@ func Execute() {
 func init() {
 	rootCmd.Flags().StringSliceVarP(&execCommands, "execute", "e", execCommands, "Execute command (can be used multiple times)")
 	generateConfig = rootCmd.Flags().BoolP("generate", "g", false, "Generate blank config")
+	shellMode = rootCmd.Flags().Bool("shell", false, "Run inline commands through the shell ($SHELL -c)")
+	tuiMode = rootCmd.Flags().Bool("tui", false, "Show a full screen interface with a log pane for every process")
+	apiAddress = rootCmd.Flags().String("api", "", "Address the HTTP control API listens on, e.g. 127.0.0.1:7070 (overrides the config)")
+	outputFormat = rootCmd.Flags().StringP("output", "o", "text", "Output format of the processes: text or json (one JSON object per line)")
+	successPolicy = rootCmd.Flags().String("success", "", "Processes that decide the exit code: all (default), first, last, or command:<name|prefix|index>")
+	socketPath = rootCmd.PersistentFlags().String("socket", ".process-party.sock", "Socket used to control a party started with up")
+	// Up runs a party with the same flags
+	upCmd.Flags().AddFlagSet(rootCmd.Flags())
 }
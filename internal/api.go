@@ -0,0 +1,461 @@
+package pp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ApiLogLines       = 1000 // Lines of output kept for every process
+	apiMaxBodySize    = 1 << 20
+	apiFollowInterval = 100 * time.Millisecond // How often followed logs are checked for new lines
+)
+
+type (
+	// Keeps the most recent lines of output of a process
+	logHistory struct {
+		mutex   sync.Mutex
+		buffer  *logBuffer
+		written int // Lines added since the history was created
+	}
+
+	// Process as reported by the HTTP control API
+	ProcessInfo struct {
+		Index       int        `json:"index"`
+		Name        string     `json:"name"`
+		Prefix      string     `json:"prefix"`
+		Command     string     `json:"command"`
+		Args        []string   `json:"args"`
+		Status      string     `json:"status"`
+		Running     bool       `json:"running"`
+		Pid         int        `json:"pid,omitempty"`       // PID of the running command
+		Restarts    int        `json:"restarts"`            // Total restarts of the process
+		StopTimeout int        `json:"stop_timeout"`        // Seconds the process is given to stop before it is killed
+		ExitCode    *int       `json:"exit_code,omitempty"` // Exit code of the last execution (-1 if it was killed or did not start)
+		ExitTime    *time.Time `json:"exit_time,omitempty"` // When the last execution ended
+	}
+
+	// Line of output as reported by the HTTP control API
+	LogEntry struct {
+		Process string    `json:"process"` // Name of the process, or the prefix if it has no name
+		Time    time.Time `json:"time"`
+		Text    string    `json:"text"`
+		Error   bool      `json:"error"` // Written to the standard error
+	}
+
+	// Body of a request sending input to a process
+	StdinRequest struct {
+		Input string `json:"input"` // Sent as a single line like input typed into process party
+	}
+
+	// Result of a control command on a single process
+	ControlResult struct {
+		Name  string `json:"name"`
+		Error string `json:"error,omitempty"`
+	}
+
+	// HTTP server to inspect and control the processes of a running party
+	ApiServer struct {
+		server   *http.Server
+		listener net.Listener
+	}
+)
+
+func newLogHistory(limit int) *logHistory {
+	return &logHistory{buffer: newLogBuffer(limit)}
+}
+
+// Adds a line of output to the history
+func (h *logHistory) add(text string, isError bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.buffer.append(logLine{text: cleanLine(text), isError: isError, time: time.Now()})
+	h.written++
+}
+
+// Returns the most recent lines, oldest first, and the number of lines written so far.
+// Returns every line when count is 0 or less
+func (h *logHistory) recent(count int) ([]LogEntry, int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	start := 0
+	if count > 0 && count < h.buffer.len() {
+		start = h.buffer.len() - count
+	}
+	return h.entries(start), h.written
+}
+
+// Returns the lines added after the history had the number of lines written, and the number of lines written so far.
+// Lines that were already dropped from the history are skipped
+func (h *logHistory) since(written int) ([]LogEntry, int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	start := h.buffer.len() - (h.written - written)
+	if start < 0 {
+		start = 0
+	}
+	return h.entries(start), h.written
+}
+
+// Returns the lines from the index onwards, the history has to be locked
+func (h *logHistory) entries(start int) []LogEntry {
+	entries := make([]LogEntry, 0, h.buffer.len()-start)
+	for i := start; i < h.buffer.len(); i++ {
+		line := h.buffer.get(i)
+		entries = append(entries, LogEntry{Time: line.time, Text: line.text, Error: line.isError})
+	}
+	return entries
+}
+
+// Returns the recent output of the process, oldest first. Returns every kept line when count is 0 or less
+func (e *ExecutionContext) RecentLogs(count int) []LogEntry {
+	entries, _ := e.history.recent(count)
+	return e.nameEntries(entries)
+}
+
+// Sets the process of the entries to the name of the process, or the prefix if it has no name
+func (e *ExecutionContext) nameEntries(entries []LogEntry) []LogEntry {
+	name := e.displayName()
+	for i := range entries {
+		entries[i].Process = name
+	}
+	return entries
+}
+
+// Returns the name of the process, or the prefix if it has no name (e.g. inline commands)
+func (e *ExecutionContext) displayName() string {
+	return e.Process.displayName()
+}
+
+// Returns the state of the process
+func (e *ExecutionContext) Info(index int) ProcessInfo {
+	e.executionMutex.RLock()
+	defer e.executionMutex.RUnlock()
+	info := ProcessInfo{
+		Index:       index,
+		Name:        e.Process.Name,
+		Prefix:      e.Process.Prefix,
+		Command:     e.Process.Command,
+		Args:        e.Process.Args,
+		Status:      e.Status.String(),
+		Running:     e.Status.IsRunning(),
+		Restarts:    int(e.restarts.Load()),
+		StopTimeout: int(e.Process.GetStopTimeout() / time.Second),
+	}
+	if info.Args == nil {
+		info.Args = []string{}
+	}
+	if info.Running {
+		info.Pid, _ = strconv.Atoi(e.Process.Pid)
+	}
+	if !e.exitTime.IsZero() {
+		exitCode, exitTime := e.ExitCode, e.exitTime
+		info.ExitCode = &exitCode
+		info.ExitTime = &exitTime
+	}
+	return info
+}
+
+// Starts the HTTP control API on the address, e.g. 127.0.0.1:7070. The API has no authentication, so
+// only loopback addresses are allowed
+func StartApi(address string, contexts []*ExecutionContext) (*ApiServer, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api address %s: %w", address, err)
+	}
+	if !isLoopbackHost(host) {
+		return nil, fmt.Errorf("the api can only listen on a loopback address like 127.0.0.1, not %s", address)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not start the api on %s: %w", address, err)
+	}
+	return serveApi(listener, contexts), nil
+}
+
+// Starts the HTTP control API on a Unix socket at the path, replacing sockets left behind by parties that did not exit cleanly
+func StartSocket(path string, contexts []*ExecutionContext) (*ApiServer, error) {
+	if _, err := os.Stat(path); err == nil {
+		if connection, err := net.Dial("unix", path); err == nil {
+			connection.Close()
+			return nil, fmt.Errorf("process party is already running on %s", path)
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the socket %s: %w", path, err)
+	}
+	return serveApi(listener, contexts), nil
+}
+
+func serveApi(listener net.Listener, contexts []*ExecutionContext) *ApiServer {
+	api := &ApiServer{
+		server:   &http.Server{Handler: NewApiHandler(contexts), ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+	}
+	go api.server.Serve(listener)
+	return api
+}
+
+// Returns the address the API is listening on
+func (a *ApiServer) Address() string {
+	return a.listener.Addr().String()
+}
+
+// Stops the API, closing all open connections
+func (a *ApiServer) Close() error {
+	err := a.server.Close()
+	// The server only closes the listener once it started serving
+	a.listener.Close()
+	return err
+}
+
+// Returns true if the host (without a port) is localhost or a loopback IP address
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// Rejects requests a browser could send for another website: cross origin requests carry an Origin header,
+// DNS rebinding sends a Host that is not a loopback address and forms cannot send JSON
+func guardApi(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("cross origin requests are not allowed"))
+			return
+		}
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopbackHost(host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed", r.Host))
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("content type has to be application/json"))
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Returns the handler of the HTTP control API. Processes are selected by name, prefix or index, and control
+// commands also accept glob patterns and all. Only requests from the local machine that are not sent by a browser
+// are accepted, see guardApi
+func NewApiHandler(contexts []*ExecutionContext) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /processes", func(w http.ResponseWriter, r *http.Request) {
+		processes := []ProcessInfo{}
+		for index, context := range contexts {
+			processes = append(processes, context.Info(index))
+		}
+		writeJson(w, http.StatusOK, processes)
+	})
+
+	mux.HandleFunc("GET /processes/{process}", func(w http.ResponseWriter, r *http.Request) {
+		context, index := findApiContext(w, r, contexts)
+		if context == nil {
+			return
+		}
+		writeJson(w, http.StatusOK, context.Info(index))
+	})
+
+	mux.HandleFunc("GET /processes/{process}/logs", func(w http.ResponseWriter, r *http.Request) {
+		context, _ := findApiContext(w, r, contexts)
+		if context == nil {
+			return
+		}
+		writeLogs(w, r, []*ExecutionContext{context})
+	})
+
+	mux.HandleFunc("GET /logs", func(w http.ResponseWriter, r *http.Request) {
+		writeLogs(w, r, contexts)
+	})
+
+	controls := map[string]func(context *ExecutionContext) error{
+		"start":   (*ExecutionContext).StartProcess,
+		"stop":    (*ExecutionContext).StopProcess,
+		"kill":    (*ExecutionContext).KillProcess,
+		"restart": (*ExecutionContext).RestartProcess,
+		"trigger": (*ExecutionContext).TriggerProcess,
+	}
+	for name, command := range controls {
+		mux.HandleFunc("POST /processes/{process}/"+name, func(w http.ResponseWriter, r *http.Request) {
+			matched, err := MatchContexts(r.PathValue("process"), contexts)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			// Run the command on every process at the same time, stopping processes can take a while
+			results := make([]ControlResult, len(matched))
+			var wg sync.WaitGroup
+			for i, context := range matched {
+				results[i].Name = context.displayName()
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := command(context); err != nil {
+						results[i].Error = err.Error()
+					}
+				}()
+			}
+			wg.Wait()
+			status := http.StatusOK
+			for _, result := range results {
+				if result.Error != "" {
+					status = http.StatusConflict
+				}
+			}
+			writeJson(w, status, results)
+		})
+	}
+
+	mux.HandleFunc("POST /processes/{process}/stdin", func(w http.ResponseWriter, r *http.Request) {
+		context, _ := findApiContext(w, r, contexts)
+		if context == nil {
+			return
+		}
+		input := StdinRequest{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodySize)).Decode(&input); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+			return
+		}
+		if !context.GetStatus().IsRunning() {
+			writeError(w, http.StatusConflict, ErrProcessNotRunning)
+			return
+		}
+		if err := context.Write(strings.TrimSuffix(strings.TrimSuffix(input.Input, "\n"), "\r")); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w, contexts)
+	})
+
+	mux.HandleFunc("POST /shutdown", func(w http.ResponseWriter, r *http.Request) {
+		go Shutdown(contexts)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	return guardApi(mux)
+}
+
+// Writes the recent output of the contexts, oldest first. Followed logs keep sending new lines
+// as JSON lines until the request is cancelled
+func writeLogs(w http.ResponseWriter, r *http.Request, contexts []*ExecutionContext) {
+	count := 100
+	if lines := r.URL.Query().Get("lines"); lines != "" {
+		var err error
+		count, err = strconv.Atoi(lines)
+		if err != nil || count < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid number of lines %s", lines))
+			return
+		}
+	}
+	follow := false
+	if value := r.URL.Query().Get("follow"); value != "" {
+		var err error
+		follow, err = strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid follow %s", value))
+			return
+		}
+	}
+
+	// Combine the output of the processes in the order it was written
+	written := make([]int, len(contexts))
+	entries := []LogEntry{}
+	for i, context := range contexts {
+		var recent []LogEntry
+		recent, written[i] = context.history.recent(count)
+		entries = append(entries, context.nameEntries(recent)...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	if count > 0 && len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+	if !follow {
+		writeJson(w, http.StatusOK, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(apiFollowInterval)
+	defer ticker.Stop()
+	for {
+		for _, entry := range entries {
+			if encoder.Encode(entry) != nil {
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		entries = entries[:0]
+		for i, context := range contexts {
+			var added []LogEntry
+			added, written[i] = context.history.since(written[i])
+			entries = append(entries, context.nameEntries(added)...)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Time.Before(entries[j].Time)
+		})
+	}
+}
+
+// Returns the single process selected by the request, or writes a not found error and returns nil
+func findApiContext(w http.ResponseWriter, r *http.Request, contexts []*ExecutionContext) (*ExecutionContext, int) {
+	target := r.PathValue("process")
+	if match := findContext(target, contexts); match != nil {
+		for index, context := range contexts {
+			if context == match {
+				return context, index
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no process matches %s", target))
+	return nil, -1
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,295 @@
+package pp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Sends the request to the handler and decodes the JSON response into the value
+func apiRequest(t *testing.T, handler http.Handler, method string, target string, body string, value any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, "http://localhost"+target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(recorder, request)
+	if value != nil {
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(value))
+	}
+	return recorder.Code
+}
+
+// Ensure that the log history keeps the most recent lines without escape sequences
+func TestLogHistory(t *testing.T) {
+	t.Parallel()
+
+	history := newLogHistory(3)
+	entries, written := history.recent(0)
+	assert.Empty(t, entries)
+	assert.Equal(t, 0, written)
+	for _, text := range []string{"1", "2", "\x1b[32m3\x1b[0m", "4"} {
+		history.add(text, text == "4")
+	}
+
+	texts := func(entries []LogEntry, _ int) []string {
+		result := []string{}
+		for _, entry := range entries {
+			result = append(result, entry.Text)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, texts(history.recent(0)))
+	assert.Equal(t, []string{"3", "4"}, texts(history.recent(2)))
+	assert.Equal(t, []string{"2", "3", "4"}, texts(history.recent(10)))
+	entries, written = history.recent(1)
+	assert.True(t, entries[0].Error)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, 4, written)
+
+	history.add("5", false)
+	assert.Equal(t, []string{"5"}, texts(history.since(4)))
+	assert.Equal(t, []string{"3", "4", "5"}, texts(history.since(0)), "Dropped lines should be skipped")
+	entries, written = history.since(5)
+	assert.Empty(t, entries)
+	assert.Equal(t, 5, written)
+}
+
+// Ensure that the API reports and selects processes
+func TestApiProcesses(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	api := Process{Name: "api", Prefix: "a", Command: "go", Args: []string{"run", "."}}
+	web := Process{Name: "web", Prefix: "w", Command: "npm"}
+	contexts := []*ExecutionContext{api.CreateContext(&wg), web.CreateContext(&wg)}
+	handler := NewApiHandler(contexts)
+
+	processes := []ProcessInfo{}
+	assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/processes", "", &processes))
+	assert.Len(t, processes, 2)
+	assert.Equal(t, "api", processes[0].Name)
+	assert.Equal(t, []string{"run", "."}, processes[0].Args)
+	assert.Equal(t, []string{}, processes[1].Args)
+	assert.Equal(t, 1, processes[1].Index)
+	assert.Equal(t, "Not started", processes[1].Status)
+	assert.Equal(t, DefaultStopTimeout, processes[1].StopTimeout)
+	assert.Nil(t, processes[1].ExitCode)
+
+	tests := []struct {
+		target string
+		name   string
+	}{
+		{"/processes/web", "web"},
+		{"/processes/a", "api"},
+		{"/processes/1", "web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			process := ProcessInfo{}
+			assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", tt.target, "", &process))
+			assert.Equal(t, tt.name, process.Name)
+		})
+	}
+
+	response := map[string]string{}
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, "GET", "/processes/db", "", &response))
+	assert.Equal(t, "no process matches db", response["error"])
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, "GET", "/processes/*", "", nil), "Globs only select processes for control commands")
+	assert.Equal(t, http.StatusMethodNotAllowed, apiRequest(t, handler, "GET", "/processes/web/restart", "", nil))
+}
+
+// Ensure that the API returns the recent output of a process
+func TestApiLogs(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	process := Process{Name: "api", Prefix: "api"}
+	context := process.CreateContext(&wg)
+	context.SetOutput(&strings.Builder{}, &strings.Builder{})
+	context.infoWriter.Write([]byte("listening on :8080\nGET /\n"))
+	context.errorWriter.Write([]byte("panic\n"))
+	handler := NewApiHandler([]*ExecutionContext{context})
+
+	logs := []LogEntry{}
+	assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/processes/api/logs", "", &logs))
+	assert.Len(t, logs, 3)
+	assert.Equal(t, "listening on :8080", logs[0].Text)
+	assert.True(t, logs[2].Error)
+
+	assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/processes/api/logs?lines=1", "", &logs))
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "panic", logs[0].Text)
+
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, "GET", "/processes/api/logs?lines=many", "", nil))
+}
+
+// Ensure that control commands report errors per process
+func TestApiControl(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	api := Process{Name: "api-1"}
+	worker := Process{Name: "api-2"}
+	contexts := []*ExecutionContext{api.CreateContext(&wg), worker.CreateContext(&wg)}
+	handler := NewApiHandler(contexts)
+
+	results := []ControlResult{}
+	assert.Equal(t, http.StatusConflict, apiRequest(t, handler, "POST", "/processes/api-*/restart", "", &results))
+	assert.Equal(t, []ControlResult{
+		{Name: "api-1", Error: ErrProcessNotStarted.Error()},
+		{Name: "api-2", Error: ErrProcessNotStarted.Error()},
+	}, results)
+
+	assert.Equal(t, http.StatusConflict, apiRequest(t, handler, "POST", "/processes/0/trigger", "", &results))
+	assert.Equal(t, ErrNoTriggers.Error(), results[0].Error)
+
+	assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, "POST", "/processes/db/stop", "", nil))
+
+	response := map[string]string{}
+	assert.Equal(t, http.StatusConflict, apiRequest(t, handler, "POST", "/processes/api-1/stdin", `{"input": "hello"}`, &response))
+	assert.Equal(t, ErrProcessNotRunning.Error(), response["error"])
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, "POST", "/processes/api-1/stdin", "hello", &response))
+	assert.Contains(t, response["error"], "invalid input")
+
+	assert.Equal(t, http.StatusAccepted, apiRequest(t, handler, "POST", "/shutdown", "", nil))
+}
+
+// Ensure that requests a browser could send for another website are rejected
+func TestApiGuard(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	process := Process{Name: "api"}
+	handler := NewApiHandler([]*ExecutionContext{process.CreateContext(&wg)})
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		origin      string
+		contentType string
+		status      int
+	}{
+		{"localhost", "POST", "http://localhost:7070/processes/api/restart", "", "application/json", http.StatusConflict},
+		{"loopback ip", "POST", "http://127.0.0.1:7070/processes/api/restart", "", "application/json; charset=utf-8", http.StatusConflict},
+		{"loopback ipv6", "POST", "http://[::1]:7070/processes/api/restart", "", "application/json", http.StatusConflict},
+		{"origin", "POST", "http://localhost:7070/processes/api/restart", "http://example.com", "application/json", http.StatusForbidden},
+		{"rebound host", "POST", "http://attacker.example.com:7070/processes/api/restart", "", "application/json", http.StatusForbidden},
+		{"private ip", "GET", "http://192.168.1.2:7070/processes", "", "", http.StatusForbidden},
+		{"text body", "POST", "http://localhost:7070/processes/api/stdin", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"form", "POST", "http://localhost:7070/shutdown", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"get without content type", "GET", "http://localhost:7070/processes", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.target, strings.NewReader(""))
+			if tt.origin != "" {
+				request.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.status, recorder.Code)
+		})
+	}
+}
+
+// Ensure that the API only listens on loopback addresses
+func TestStartApiLoopback(t *testing.T) {
+	t.Parallel()
+
+	for _, address := range []string{":0", "0.0.0.0:0", "192.168.1.2:0"} {
+		_, err := StartApi(address, []*ExecutionContext{})
+		assert.ErrorContains(t, err, "loopback address", address)
+	}
+	_, err := StartApi("127.0.0.1", []*ExecutionContext{})
+	assert.ErrorContains(t, err, "invalid api address")
+
+	api, err := StartApi("127.0.0.1:0", []*ExecutionContext{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.NoError(t, api.Close())
+}
+
+// Ensure that the output of every process is combined in the order it was written
+func TestApiCombinedLogs(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	api := Process{Name: "api"}
+	web := Process{Prefix: "web"}
+	contexts := []*ExecutionContext{api.CreateContext(&wg), web.CreateContext(&wg)}
+	for _, context := range contexts {
+		context.SetOutput(&strings.Builder{}, &strings.Builder{})
+	}
+	contexts[0].infoWriter.Write([]byte("1\n"))
+	contexts[1].infoWriter.Write([]byte("2\n"))
+	contexts[0].infoWriter.Write([]byte("3\n"))
+	handler := NewApiHandler(contexts)
+
+	logs := []LogEntry{}
+	assert.Equal(t, http.StatusOK, apiRequest(t, handler, "GET", "/logs?lines=2", "", &logs))
+	assert.Equal(t, []LogEntry{
+		{Process: "web", Time: logs[0].Time, Text: "2"},
+		{Process: "api", Time: logs[1].Time, Text: "3"},
+	}, logs)
+	assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, "GET", "/logs?follow=maybe", "", nil))
+}
+
+// Ensure that followed logs keep sending new lines until the request is cancelled
+func TestApiFollowLogs(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	process := Process{Name: "api"}
+	context := process.CreateContext(&wg)
+	context.SetOutput(&strings.Builder{}, &strings.Builder{})
+	context.infoWriter.Write([]byte("old\n"))
+	server := httptest.NewServer(NewApiHandler([]*ExecutionContext{context}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/processes/api/logs?lines=1&follow=true")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer response.Body.Close()
+	assert.Equal(t, "application/x-ndjson", response.Header.Get("Content-Type"))
+	decoder := json.NewDecoder(response.Body)
+
+	entry := LogEntry{}
+	assert.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, "old", entry.Text)
+	context.infoWriter.Write([]byte("new\n"))
+	assert.NoError(t, decoder.Decode(&entry))
+	assert.Equal(t, "new", entry.Text)
+	assert.Equal(t, "api", entry.Process)
+}
+
+// Ensure that sockets left behind by parties that did not exit cleanly are replaced
+func TestStartSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "pp.sock")
+	assert.NoError(t, os.WriteFile(path, []byte{}, 0644))
+	socket, err := StartSocket(path, []*ExecutionContext{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = StartSocket(path, []*ExecutionContext{})
+	assert.ErrorContains(t, err, "process party is already running")
+
+	assert.NoError(t, socket.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "Closing should remove the socket")
+}
@@ -0,0 +1,74 @@
+package pp
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+type Backoff struct {
+	Initial    int     `toml:"initial_ms" json:"initial_ms" yaml:"initial_ms"` // Delay before the first restart (defaults to restart_delay)
+	Multiplier float64 `toml:"multiplier" json:"multiplier" yaml:"multiplier"` // Factor the delay grows by after every restart (default 1, a fixed delay)
+	Max        int     `toml:"max_ms" json:"max_ms" yaml:"max_ms"`             // Longest delay between restarts (default 60000 when the delay grows)
+	Jitter     float64 `toml:"jitter" json:"jitter" yaml:"jitter"`             // Fraction of the delay to randomly add or remove (0-1)
+}
+
+const (
+	DefaultBackoffMax      = 60000 // Milliseconds
+	CrashLoopThreshold     = 3     // Consecutive quick failures before a process is crash looping
+	DefaultCrashLoopWindow = 60    // Seconds a run has to stay up to not be a quick failure, if restart_window is not set
+)
+
+// Validates the backoff configuration
+func (b *Backoff) Validate() error {
+	if b.Initial < 0 || b.Max < 0 {
+		return errors.New("backoff delays cannot be negative")
+	}
+	if b.Multiplier != 0 && b.Multiplier < 1 {
+		return errors.New("backoff multiplier has to be 1 or larger")
+	}
+	if b.Jitter < 0 || b.Jitter > 1 {
+		return errors.New("backoff jitter has to be between 0 and 1")
+	}
+	return nil
+}
+
+// Returns the delay before a restart, where attempt is the number of restarts since the process last ran stable
+func (p *Process) GetRestartDelay(attempt int) time.Duration {
+	backoff := p.Backoff
+	delay := float64(time.Duration(p.RestartDelay) * time.Second)
+	if backoff.Initial > 0 {
+		delay = float64(time.Duration(backoff.Initial) * time.Millisecond)
+	}
+
+	if backoff.Multiplier > 1 {
+		delay *= math.Pow(backoff.Multiplier, float64(attempt))
+		max := float64(time.Duration(DefaultBackoffMax) * time.Millisecond)
+		if backoff.Max > 0 {
+			max = float64(time.Duration(backoff.Max) * time.Millisecond)
+		}
+		delay = math.Min(delay, max)
+	} else if backoff.Max > 0 {
+		delay = math.Min(delay, float64(time.Duration(backoff.Max)*time.Millisecond))
+	}
+
+	if backoff.Jitter > 0 {
+		delay += delay * backoff.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Returns how long the process has to run before it is considered stable, 0 if disabled
+func (p *Process) GetRestartWindow() time.Duration {
+	return time.Duration(p.RestartWindow) * time.Second
+}
+
+// Returns true if a failed run ended within the restart window (or DefaultCrashLoopWindow), only quick failures count towards crash looping
+func (p *Process) isQuickFailure(uptime time.Duration) bool {
+	window := p.GetRestartWindow()
+	if window <= 0 {
+		window = time.Duration(DefaultCrashLoopWindow) * time.Second
+	}
+	return uptime < window
+}
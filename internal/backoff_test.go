@@ -0,0 +1,93 @@
+package pp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that the restart delay grows, is capped, and falls back to the restart delay
+func TestGetRestartDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		restartDelay int
+		backoff      Backoff
+		delays       []time.Duration
+	}{
+		{"restart delay", 2, Backoff{}, []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second}},
+		{"initial overrides restart delay", 2, Backoff{Initial: 100}, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}},
+		{"exponential", 0, Backoff{Initial: 100, Multiplier: 2}, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}},
+		{"exponential from restart delay", 1, Backoff{Multiplier: 3}, []time.Duration{time.Second, 3 * time.Second, 9 * time.Second}},
+		{"capped", 0, Backoff{Initial: 100, Multiplier: 2, Max: 300}, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}},
+		{"default cap", 0, Backoff{Initial: 1000, Multiplier: 10}, []time.Duration{time.Second, 10 * time.Second, time.Minute, time.Minute}},
+		{"fixed delay capped", 5, Backoff{Max: 1000}, []time.Duration{time.Second, time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			process := Process{RestartDelay: tt.restartDelay, Backoff: tt.backoff}
+			for attempt, expected := range tt.delays {
+				assert.Equal(t, expected, process.GetRestartDelay(attempt), "Attempt %d", attempt)
+			}
+		})
+	}
+}
+
+// Ensure that jitter keeps the delay within the configured fraction
+func TestGetRestartDelayJitter(t *testing.T) {
+	t.Parallel()
+
+	process := Process{Backoff: Backoff{Initial: 1000, Jitter: 0.25}}
+	for range 100 {
+		delay := process.GetRestartDelay(0)
+		assert.GreaterOrEqual(t, delay, 750*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1250*time.Millisecond)
+	}
+}
+
+// Ensure that invalid backoff settings are rejected
+func TestBackoffValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		err     string
+	}{
+		{"empty", Backoff{}, ""},
+		{"full", Backoff{Initial: 100, Multiplier: 1.5, Max: 1000, Jitter: 1}, ""},
+		{"negative initial", Backoff{Initial: -1}, "backoff delays cannot be negative"},
+		{"negative max", Backoff{Max: -1}, "backoff delays cannot be negative"},
+		{"shrinking", Backoff{Multiplier: 0.5}, "backoff multiplier has to be 1 or larger"},
+		{"jitter too large", Backoff{Jitter: 1.5}, "backoff jitter has to be between 0 and 1"},
+		{"negative jitter", Backoff{Jitter: -0.1}, "backoff jitter has to be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.backoff.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+// Ensure that only failures within the restart window count towards crash looping
+func TestQuickFailure(t *testing.T) {
+	t.Parallel()
+
+	process := Process{}
+	assert.True(t, process.isQuickFailure(time.Second))
+	assert.False(t, process.isQuickFailure(time.Duration(DefaultCrashLoopWindow)*time.Second))
+	assert.False(t, process.isQuickFailure(3*time.Hour), "Failures after hours of uptime are not crash looping")
+
+	process.RestartWindow = 5
+	assert.True(t, process.isQuickFailure(4*time.Second))
+	assert.False(t, process.isQuickFailure(5*time.Second))
+}
@@ -21,16 +21,20 @@ type (
 
 	FileSystemTrigger struct {
 		NonRecursive   bool     `toml:"non_recursive" json:"non_recursive" yaml:"non_recursive"` // Do not recursively watch new directories
-		DebounceTime   uint16   `toml:"debounce_ms" json:"debounce_ms" yaml:"debounce_ms"`       // Time between fs triggers
+		DebounceTime   *uint16  `toml:"debounce_ms" json:"debounce_ms" yaml:"debounce_ms"`       // Time without changes before the fs trigger fires (default 50, 0 fires on every change)
+		MaxWait        uint16   `toml:"max_wait_ms" json:"max_wait_ms" yaml:"max_wait_ms"`       // Longest time the fs trigger waits after the first change (0 waits until changes stop)
 		Watch          []string `toml:"watch" json:"watch" yaml:"watch"`                         // List of directories/folders to watch
 		Ignore         []string `toml:"ignore" json:"ignore" yaml:"ignore"`                      // List of directories/folders to ignore
 		ContainFilters []string `toml:"filter_for" json:"filter_for" yaml:"filter_for"`          // Include or exclude files
+		Gitignore      bool     `toml:"gitignore" json:"gitignore" yaml:"gitignore"`             // Ignore paths listed in .gitignore and .ignore files in watched directories
+		Events         []string `toml:"events" json:"events" yaml:"events"`                      // Operations that trigger the process: create, write, remove, rename and chmod (all but chmod by default)
 	}
 
 	ProcessTrigger struct {
 		OnStart    []string `toml:"on_start" json:"on_start" yaml:"on_start"`          // Trigger run when listed process started successfully
 		OnComplete []string `toml:"on_complete" json:"on_complete" yaml:"on_complete"` // Trigger run when listed process exits successfully
 		OnError    []string `toml:"on_error" json:"on_error" yaml:"on_error"`          // Trigger run when listed process errors
+		AllowLoop  bool     `toml:"allow_loop" json:"allow_loop" yaml:"allow_loop"`    // Allow the process to be part of a trigger loop
 	}
 
 	Trigger struct {
@@ -50,22 +54,49 @@ type (
 		DisplayPid  bool       `toml:"show_pid" json:"show_pid" yaml:"show_pid"`                   // Show the PID of the process
 		StartStream string     `toml:"stdin_on_start" json:"stdin_on_start" yaml:"stdin_on_start"` // Stream sequence to the command on startup
 		Silent      bool       `toml:"silent" json:"silent" yaml:"silent"`                         // Mute output from the command
+		LogFile     LogFile    `toml:"log_file" json:"log_file" yaml:"log_file"`                   // Also write the output to a file (overrides the config setting)
+		Cwd         string     `toml:"cwd" json:"cwd" yaml:"cwd"`                                  // Working directory, relative to the config file
+		Shell       *bool      `toml:"shell" json:"shell" yaml:"shell"`                            // Run the command through the shell (overrides the config setting)
+		// Environment
+		Env      map[string]string `toml:"env" json:"env" yaml:"env"`                // Environment variables set for the command
+		EnvFiles []string          `toml:"env_file" json:"env_file" yaml:"env_file"` // Dotenv files loaded into the environment
 		// Behaviour
-		Trigger         Trigger     `toml:"trigger" json:"trigger" yaml:"trigger"`                                           // Any triggers that can start the process
-		Delay           int         `toml:"delay" json:"delay" yaml:"delay"`                                                 // Delay on starting the process
-		RestartDelay    int         `toml:"restart_delay" json:"restart_delay" yaml:"restart_delay"`                         // Delay before restarting the process
-		OnFailure       ExitCommand `toml:"on_failure" json:"on_failure" yaml:"on_failure"`                                  // Exit behaviour on process failure
-		OnComplete      ExitCommand `toml:"on_complete,omitempty" json:"on_complete,omitempty" yaml:"on_complete,omitempty"` // Exit behaviour on successful exit
-		RestartAttempts int         `toml:"restart_attempts" json:"restart_attempts" yaml:"restart_attempts"`                // Restart attempts for the process (<0 to always restart)
+		Trigger         Trigger      `toml:"trigger" json:"trigger" yaml:"trigger"`                                           // Any triggers that can start the process
+		Delay           int          `toml:"delay" json:"delay" yaml:"delay"`                                                 // Delay on starting the process
+		RestartDelay    int          `toml:"restart_delay" json:"restart_delay" yaml:"restart_delay"`                         // Delay before restarting the process
+		OnFailure       ExitCommand  `toml:"on_failure" json:"on_failure" yaml:"on_failure"`                                  // Exit behaviour on process failure
+		OnComplete      ExitCommand  `toml:"on_complete,omitempty" json:"on_complete,omitempty" yaml:"on_complete,omitempty"` // Exit behaviour on successful exit
+		RestartAttempts int          `toml:"restart_attempts" json:"restart_attempts" yaml:"restart_attempts"`                // Restart attempts for the process (<0 to always restart)
+		RestartWindow   int          `toml:"restart_window" json:"restart_window" yaml:"restart_window"`                      // Seconds of uptime after which restart attempts and backoff reset (0 to never reset)
+		Backoff         Backoff      `toml:"backoff" json:"backoff" yaml:"backoff"`                                           // Delay growth between restarts
+		StopSignal      string       `toml:"stop_signal" json:"stop_signal" yaml:"stop_signal"`                               // Signal sent to gracefully stop the process (default SIGINT)
+		StopTimeout     int          `toml:"stop_timeout" json:"stop_timeout" yaml:"stop_timeout"`                            // Seconds to wait for the process to stop before killing it (default 10)
+		HealthCheck     HealthCheck  `toml:"healthcheck" json:"healthcheck" yaml:"healthcheck"`                               // Checks used to determine if the process is healthy
+		DependsOn       []Dependency `toml:"depends_on" json:"depends_on" yaml:"depends_on"`                                  // Processes that have to be ready before the process starts
+		// Exit codes
+		SuccessExitCodes    []int `toml:"success_exit_codes" json:"success_exit_codes" yaml:"success_exit_codes"`             // Exit codes other than 0 that count as completing successfully
+		RestartOnExitCodes  []int `toml:"restart_on_exit_codes" json:"restart_on_exit_codes" yaml:"restart_on_exit_codes"`    // Exit codes that always restart the process
+		BuzzkillOnExitCodes []int `toml:"buzzkill_on_exit_codes" json:"buzzkill_on_exit_codes" yaml:"buzzkill_on_exit_codes"` // Exit codes that always buzzkill the other processes
 		// Runtime
-		ShowTimestamp bool   `toml:"-" json:"-" yaml:"-"` // Show timestamp private setting obtained from config
-		Pid           string `toml:"-" json:"-" yaml:"-"` // Private PID value assigned on process successful start
+		ShowTimestamp  bool              `toml:"-" json:"-" yaml:"-"` // Show timestamp private setting obtained from config
+		Pid            string            `toml:"-" json:"-" yaml:"-"` // Private PID value assigned on process successful start
+		GlobalEnv      map[string]string `toml:"-" json:"-" yaml:"-"` // Environment variables obtained from config
+		GlobalEnvFiles []string          `toml:"-" json:"-" yaml:"-"` // Dotenv files obtained from config
+		GlobalShell    bool              `toml:"-" json:"-" yaml:"-"` // Shell mode obtained from config
+		GlobalLogFile  LogFile           `toml:"-" json:"-" yaml:"-"` // Log file obtained from config
 	}
 
 	Config struct {
-		Processes     []Process `toml:"processes" json:"processes" yaml:"processes"`
-		ShowTimestamp bool      `toml:"show_timestamp" json:"show_timestamp" yaml:"show_timestamp"`
-		filePresent   bool      `toml:"-" json:"-" yaml:"-"`
+		Processes     []Process         `toml:"processes" json:"processes" yaml:"processes"`
+		ShowTimestamp bool              `toml:"show_timestamp" json:"show_timestamp" yaml:"show_timestamp"`
+		Env           map[string]string `toml:"env" json:"env" yaml:"env"`                // Environment variables set for every process
+		EnvFiles      []string          `toml:"env_file" json:"env_file" yaml:"env_file"` // Dotenv files loaded for every process
+		Shell         bool              `toml:"shell" json:"shell" yaml:"shell"`          // Run every command through the shell
+		Success       SuccessPolicy     `toml:"success" json:"success" yaml:"success"`    // Processes that decide the exit code (all, first, last, command:<name>)
+		Api           string            `toml:"api" json:"api" yaml:"api"`                // Address the HTTP control API listens on, e.g. 127.0.0.1:7070 (disabled when empty)
+		LogFile       LogFile           `toml:"log_file" json:"log_file" yaml:"log_file"` // Also write the output of every process to a file, {name} is replaced by the process name
+		filePresent   bool              `toml:"-" json:"-" yaml:"-"`
+		directory     string            `toml:"-" json:"-" yaml:"-"` // Directory containing the parsed config file
 	}
 )
 
@@ -85,25 +116,38 @@ const (
 	ColourCmdMagenta ColourCode = "magenta"
 )
 
+// Returns the name of the process, or its prefix if it has no name
+func (p *Process) displayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Prefix
+}
+
 // Gets the coloured print function for the writer
 func (p *Process) GetFgColour() func(format string, a ...interface{}) string {
+	return color.New(p.getFgAttribute()).SprintfFunc()
+}
+
+// Gets the colour of the prefix
+func (p *Process) getFgAttribute() color.Attribute {
 	switch p.Color {
 	case ColourCmdYellow:
-		return color.YellowString
+		return color.FgYellow
 	case ColourCmdBlue:
-		return color.BlueString
+		return color.FgBlue
 	case ColourCmdGreen:
-		return color.GreenString
+		return color.FgGreen
 	case ColourCmdRed:
-		return color.RedString
+		return color.FgRed
 	case ColourCmdCyan:
-		return color.CyanString
+		return color.FgCyan
 	case ColourCmdWhite:
-		return color.WhiteString
+		return color.FgWhite
 	case ColourCmdMagenta:
-		return color.MagentaString
+		return color.FgMagenta
 	default:
-		return color.WhiteString
+		return color.FgWhite
 	}
 }
 
@@ -119,6 +163,14 @@ func (p *Process) HasProcessTrigger() bool {
 		len(p.Trigger.Process.OnError) > 0
 }
 
+// Returns if the process command should be run through the shell
+func (p *Process) UseShell() bool {
+	if p.Shell != nil {
+		return *p.Shell
+	}
+	return p.GlobalShell
+}
+
 // Returns if the process has an fs trigger or process trigger
 func (t *Process) HasTrigger() bool {
 	return t.HasFsTrigger() || t.HasProcessTrigger()
@@ -128,26 +180,38 @@ func (c *Config) GenerateExampleConfig(path string) error {
 
 	fmt.Printf("Generating config - %s\n", path)
 
+	debounceTime := uint16(DefaultDebounceTime)
 	exampleProcess := Process{
-		Name:            "my process",
-		Prefix:          "EXAMPLE",
-		Command:         "ls",
-		OnFailure:       "wait",
-		OnComplete:      "wait",
-		Args:            []string{},
-		Color:           ColourCmdGreen,
-		DisplayPid:      false,
-		Silent:          false,
-		Delay:           0,
-		RestartDelay:    0,
-		RestartAttempts: 0,
-		StartStream:     "",
+		Name:                "my process",
+		Prefix:              "EXAMPLE",
+		Command:             "ls",
+		OnFailure:           "wait",
+		OnComplete:          "wait",
+		Args:                []string{},
+		Color:               ColourCmdGreen,
+		DisplayPid:          false,
+		Silent:              false,
+		Delay:               0,
+		RestartDelay:        0,
+		RestartAttempts:     0,
+		RestartWindow:       0,
+		StopSignal:          DefaultStopSignal,
+		StopTimeout:         DefaultStopTimeout,
+		StartStream:         "",
+		Cwd:                 "",
+		Env:                 map[string]string{},
+		EnvFiles:            []string{},
+		DependsOn:           []Dependency{},
+		SuccessExitCodes:    []int{},
+		RestartOnExitCodes:  []int{},
+		BuzzkillOnExitCodes: []int{},
 		Trigger: Trigger{
 			FileSystem: FileSystemTrigger{
-				DebounceTime:   50,
+				DebounceTime:   &debounceTime,
 				Watch:          []string{},
 				Ignore:         []string{},
 				ContainFilters: []string{},
+				Events:         []string{},
 			},
 			Process: ProcessTrigger{
 				OnStart:    []string{},
@@ -211,7 +275,10 @@ func (c *Config) ScanDir(path string) (string, error) {
 	}
 	for _, directory := range dirs {
 		if !directory.IsDir() {
-			if strings.Contains(directory.Name(), "process-party") {
+			// Skip other process party files, e.g. the log and socket of a detached party
+			extension := strings.ToLower(filepath.Ext(directory.Name()))
+			isConfig := extension == ".json" || extension == ".toml" || extension == ".yaml" || extension == ".yml"
+			if isConfig && strings.Contains(directory.Name(), "process-party") {
 				return directory.Name(), nil
 			}
 		}
@@ -221,18 +288,30 @@ func (c *Config) ScanDir(path string) (string, error) {
 	return "", nil
 }
 
+// Resolves a relative path against the directory of the parsed config file
+func (c *Config) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || c.directory == "" {
+		return path
+	}
+	return filepath.Join(c.directory, path)
+}
+
 // Parses an inline command (not config related) to be added to the config
 func (c *Config) ParseInlineCmd(cmd string) error {
-	s := strings.Split(cmd, " ")
+	s, err := splitCommand(cmd)
+	if err != nil {
+		// Shells have their own quoting rules, so only the prefix relies on splitting
+		if !c.Shell {
+			return err
+		}
+		s = strings.Fields(cmd)
+	}
 	if len(s) == 0 {
 		return errors.New("empty command provided")
 	}
 	command := s[0]
-	args := []string{}
+	args := s[1:]
 	prefix := command
-	if len(s) > 1 {
-		args = s[:1]
-	}
 
 	// Count how many processes have the same name and increment it for the prefix
 	count := 0
@@ -252,6 +331,16 @@ func (c *Config) ParseInlineCmd(cmd string) error {
 		Command:    command,
 		Args:       args,
 		Prefix:     prefix,
+		// Set general values
+		GlobalEnv:      c.Env,
+		GlobalEnvFiles: c.EnvFiles,
+		GlobalShell:    c.Shell,
+		GlobalLogFile:  c.LogFile,
+	}
+	// The shell receives the command exactly as it was written
+	if c.Shell {
+		p.Command = cmd
+		p.Args = []string{}
 	}
 	c.Processes = append(c.Processes, p)
 
@@ -279,6 +368,12 @@ func (c *Config) ParseFile(path string, silent bool) error {
 		color.HiBlack("\nFound process-party config file: %s \n\n", path)
 	}
 
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c.directory = filepath.Dir(absPath)
+
 	extensions := strings.Split(path, ".")
 	buffer, err := os.ReadFile(path)
 	if err != nil {
@@ -315,6 +410,16 @@ func (c *Config) ParseFile(path string, silent bool) error {
 
 	uniqueChecks := map[string]bool{}
 
+	for i := range c.EnvFiles {
+		c.EnvFiles[i] = c.resolvePath(c.EnvFiles[i])
+	}
+	if c.LogFile.Path != "" {
+		c.LogFile.Path = c.resolvePath(c.LogFile.Path)
+	}
+	if err := c.LogFile.Validate(); err != nil {
+		return fmt.Errorf("invalid log_file: %w", err)
+	}
+
 	if !silent {
 		color.HiGreen("Found %d processes in %s", len(c.Processes), path)
 		color.HiBlack("Process tasks:")
@@ -342,15 +447,64 @@ func (c *Config) ParseFile(path string, silent bool) error {
 			}
 		}
 
-		// Fix broken commands (command is 1 value, append the rest to args)
-		cmdSplit := strings.Split(c.Processes[i].Command, " ")
-		if len(cmdSplit) > 1 {
-			c.Processes[i].Args = append(strings.Split(c.Processes[i].Command, " ")[:1], c.Processes[i].Args...)
-			c.Processes[i].Command = strings.Split(c.Processes[i].Command, " ")[0]
+		// Resolve paths relative to the config file
+		c.Processes[i].Cwd = c.resolvePath(c.Processes[i].Cwd)
+		for j := range c.Processes[i].EnvFiles {
+			c.Processes[i].EnvFiles[j] = c.resolvePath(c.Processes[i].EnvFiles[j])
+		}
+		if c.Processes[i].LogFile.Path != "" {
+			c.Processes[i].LogFile.Path = c.resolvePath(c.Processes[i].LogFile.Path)
 		}
 
 		// Set general values
 		c.Processes[i].ShowTimestamp = c.ShowTimestamp
+		c.Processes[i].GlobalEnv = c.Env
+		c.Processes[i].GlobalEnvFiles = c.EnvFiles
+		c.Processes[i].GlobalShell = c.Shell
+		c.Processes[i].GlobalLogFile = c.LogFile
+
+		// Split commands containing arguments (command is 1 value, prepend the rest to args)
+		// Shell commands are passed to the shell as they are written
+		if !c.Processes[i].UseShell() {
+			cmdSplit, err := splitCommand(c.Processes[i].Command)
+			if err != nil {
+				return fmt.Errorf("could not parse the command of process %s: %w", c.Processes[i].Name, err)
+			}
+			if len(cmdSplit) > 1 {
+				c.Processes[i].Args = append(cmdSplit[1:], c.Processes[i].Args...)
+				c.Processes[i].Command = cmdSplit[0]
+			}
+		}
+
+		// Validate the stop signal
+		if _, err := c.Processes[i].GetStopSignal(); err != nil {
+			return fmt.Errorf("invalid stop_signal on process %s: %w", c.Processes[i].Name, err)
+		}
+
+		// Validate the fs trigger events
+		if _, err := c.Processes[i].Trigger.FileSystem.GetEvents(); err != nil {
+			return fmt.Errorf("invalid events on process %s: %w", c.Processes[i].Name, err)
+		}
+
+		// Validate the exit code actions
+		if err := c.Processes[i].ValidateExitCodes(); err != nil {
+			return fmt.Errorf("invalid exit codes on process %s: %w", c.Processes[i].Name, err)
+		}
+
+		// Validate the restart backoff
+		if err := c.Processes[i].Backoff.Validate(); err != nil {
+			return fmt.Errorf("invalid backoff on process %s: %w", c.Processes[i].Name, err)
+		}
+
+		// Validate the log file
+		if err := c.Processes[i].LogFile.Validate(); err != nil {
+			return fmt.Errorf("invalid log_file on process %s: %w", c.Processes[i].Name, err)
+		}
+
+		// Validate the health check
+		if err := c.Processes[i].HealthCheck.Validate(); err != nil {
+			return fmt.Errorf("invalid healthcheck on process %s: %w", c.Processes[i].Name, err)
+		}
 
 		// Check for duplicate uniques
 		if uniqueChecks[c.Processes[i].Name] {
@@ -0,0 +1,150 @@
+package pp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+type (
+	controlCommand int
+
+	// Request sent to the monitor of a context, the result is sent back once the command was applied
+	controlRequest struct {
+		command controlCommand
+		result  chan error
+	}
+)
+
+const (
+	controlStart controlCommand = iota
+	controlStop
+	controlKill
+	controlRestart
+	controlTrigger
+)
+
+var (
+	ErrProcessRunning      = errors.New("process is already running")
+	ErrProcessNotRunning   = errors.New("process is not running")
+	ErrProcessNotStarted   = errors.New("process party has not started the process")
+	ErrProcessEnded        = errors.New("process has ended and cannot be controlled")
+	ErrWaitingDependencies = errors.New("process is waiting for its dependencies")
+	ErrNoTriggers          = errors.New("process has no triggers")
+)
+
+// Starts the process if it is stopped or waiting for a trigger
+func (e *ExecutionContext) StartProcess() error {
+	return e.sendControl(controlStart)
+}
+
+// Gracefully stops the process without running its exit actions, it stays stopped until started again
+func (e *ExecutionContext) StopProcess() error {
+	return e.sendControl(controlStop)
+}
+
+// Immediately kills the process without running its exit actions, it stays stopped until started again
+func (e *ExecutionContext) KillProcess() error {
+	return e.sendControl(controlKill)
+}
+
+// Restarts the running process, or starts it if it is stopped or waiting for a trigger. Does not use up restart attempts
+func (e *ExecutionContext) RestartProcess() error {
+	return e.sendControl(controlRestart)
+}
+
+// Triggers the process as if one of its triggers fired
+func (e *ExecutionContext) TriggerProcess() error {
+	if len(e.triggers) == 0 {
+		return ErrNoTriggers
+	}
+	return e.sendControl(controlTrigger)
+}
+
+// Sends a signal to the running command and its children
+func (e *ExecutionContext) Signal(sig os.Signal) error {
+	e.executionMutex.RLock()
+	cmd := e.cmd
+	processDone := e.processDone
+	e.executionMutex.RUnlock()
+	if cmd == nil || cmd.Process == nil || processDone == nil {
+		return ErrProcessNotRunning
+	}
+	select {
+	case <-processDone:
+		return ErrProcessNotRunning
+	default:
+	}
+
+	e.infoWriter.Printf("Sending %s", sig)
+	return signalProcessGroup(cmd.Process.Pid, sig)
+}
+
+// Closes the standard input of the running command, as if Ctrl+D was pressed in its terminal
+func (e *ExecutionContext) CloseInput() error {
+	e.executionMutex.RLock()
+	stdinPipe := e.stdinPipe
+	processDone := e.processDone
+	e.executionMutex.RUnlock()
+	if stdinPipe == nil || processDone == nil {
+		return ErrProcessNotRunning
+	}
+	select {
+	case <-processDone:
+		return ErrProcessNotRunning
+	default:
+	}
+
+	e.infoWriter.Printf("Closing input")
+	return stdinPipe.Close()
+}
+
+// Sends the command to the monitor of the context and waits for the result
+func (e *ExecutionContext) sendControl(command controlCommand) error {
+	if !e.started.Load() {
+		return ErrProcessNotStarted
+	}
+	if e.GetStatus() == ProcessStatusWaitingDependencies {
+		return ErrWaitingDependencies
+	}
+
+	request := controlRequest{command: command, result: make(chan error, 1)}
+	select {
+	case e.control <- request:
+		return <-request.result
+	case <-e.done:
+		return ErrProcessEnded
+	}
+}
+
+// Returns the contexts selected by the name, prefix or index. Patterns with glob characters (*, ?, [...])
+// select every process with a matching name or prefix, and all selects every process
+func MatchContexts(pattern string, contexts []*ExecutionContext) ([]*ExecutionContext, error) {
+	if pattern == "all" {
+		return contexts, nil
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if context := findContext(pattern, contexts); context != nil {
+			return []*ExecutionContext{context}, nil
+		}
+		return nil, fmt.Errorf("no process matches %s", pattern)
+	}
+
+	matched := []*ExecutionContext{}
+	for _, context := range contexts {
+		nameMatch, err := path.Match(pattern, context.Process.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		prefixMatch, _ := path.Match(pattern, context.Process.Prefix)
+		if nameMatch || prefixMatch {
+			matched = append(matched, context)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no process matches %s", pattern)
+	}
+	return matched, nil
+}
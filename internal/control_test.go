@@ -0,0 +1,54 @@
+package pp
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that processes are selected by name, prefix, index and glob patterns
+func TestMatchContexts(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	contexts := []*ExecutionContext{}
+	for _, names := range [][2]string{{"api", "a"}, {"api-worker", "w1"}, {"web", "w2"}} {
+		process := Process{Name: names[0], Prefix: names[1]}
+		contexts = append(contexts, process.CreateContext(&wg))
+	}
+
+	tests := []struct {
+		pattern string
+		matched []string
+		err     string
+	}{
+		{"api", []string{"api"}, ""},
+		{"w2", []string{"web"}, ""},
+		{"1", []string{"api-worker"}, ""},
+		{"api*", []string{"api", "api-worker"}, ""},
+		{"w?", []string{"api-worker", "web"}, ""},
+		{"all", []string{"api", "api-worker", "web"}, ""},
+		{"*", []string{"api", "api-worker", "web"}, ""},
+		{"db", nil, "no process matches db"},
+		{"db-*", nil, "no process matches db-*"},
+		{"3", nil, "no process matches 3"},
+		{"[", nil, "invalid pattern [: syntax error in pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matched, err := MatchContexts(tt.pattern, contexts)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			names := []string{}
+			for _, context := range matched {
+				names = append(names, context.Process.Name)
+			}
+			assert.Equal(t, tt.matched, names)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package pp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	DependencyCondition string
+
+	Dependency struct {
+		Process   string              `toml:"process" json:"process" yaml:"process"`       // Name of the process that has to be ready first
+		Condition DependencyCondition `toml:"condition" json:"condition" yaml:"condition"` // State the process has to reach (default started)
+	}
+
+	// Tracks when a dependency of a process reaches its condition
+	dependencyMonitor struct {
+		name        string
+		condition   DependencyCondition
+		ready       chan struct{} // Closed once the condition is met
+		unreachable chan struct{} // Closed if the dependency exits without meeting the condition
+	}
+)
+
+const (
+	DependencyStarted               DependencyCondition = "started"
+	DependencyHealthy               DependencyCondition = "healthy"
+	DependencyCompletedSuccessfully DependencyCondition = "completed_successfully"
+)
+
+// Returns the condition of the dependency, started if it is not set
+func (d *Dependency) GetCondition() DependencyCondition {
+	if d.Condition == "" {
+		return DependencyStarted
+	}
+	return d.Condition
+}
+
+// Finds a cycle in a directed graph, returning the path of the cycle (first and last node are the same) or nil.
+// Nodes are visited in the given order so that the reported cycle is deterministic
+func findCycle(nodes []string, edges map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		path = append(path, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case visiting:
+				// The cycle starts where the node was first entered
+				for i := range path {
+					if path[i] == next {
+						return append(append([]string{}, path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = visited
+		return nil
+	}
+
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// Orders the contexts so that every process comes after the processes it depends on, otherwise keeping the original order
+func startupOrder(contexts []*ExecutionContext) []*ExecutionContext {
+	ordered := make([]*ExecutionContext, 0, len(contexts))
+	added := map[*ExecutionContext]bool{}
+
+	for len(ordered) < len(contexts) {
+		progress := false
+		for _, context := range contexts {
+			if added[context] {
+				continue
+			}
+			ready := true
+			for _, dependency := range context.Process.DependsOn {
+				for _, other := range contexts {
+					if other.Process.Name == dependency.Process && !added[other] && other != context {
+						ready = false
+					}
+				}
+			}
+			if ready {
+				ordered = append(ordered, context)
+				added[context] = true
+				progress = true
+				break
+			}
+		}
+		// Cycles are rejected when linking, keep the remaining order if one slipped through
+		if !progress {
+			for _, context := range contexts {
+				if !added[context] {
+					ordered = append(ordered, context)
+					added[context] = true
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// Monitors the process for the condition. The monitor keeps listening after the condition is met so the process never blocks on it
+func (e *ExecutionContext) createDependencyMonitor(condition DependencyCondition) *dependencyMonitor {
+	monitor := &dependencyMonitor{
+		name:        e.Process.Name,
+		condition:   condition,
+		ready:       make(chan struct{}),
+		unreachable: make(chan struct{}),
+	}
+	statusChannel := e.GetProcessNotificationChannel()
+
+	go func() {
+		resolved := false
+		failed := false
+		resolve := func(channel chan struct{}) {
+			if !resolved {
+				resolved = true
+				close(channel)
+			}
+		}
+
+		for status := range statusChannel {
+			switch status {
+			case ProcessStatusRunning:
+				failed = false
+				if condition == DependencyStarted {
+					resolve(monitor.ready)
+				}
+			case ProcessStatusHealthy:
+				if condition == DependencyHealthy {
+					resolve(monitor.ready)
+				}
+			case ProcessStatusFailed:
+				failed = true
+			case ProcessStatusExited:
+				if condition == DependencyCompletedSuccessfully && !failed {
+					resolve(monitor.ready)
+				}
+				resolve(monitor.unreachable)
+			}
+		}
+		resolve(monitor.unreachable)
+	}()
+
+	return monitor
+}
+
+// Links the dependencies of every context, ensuring that they exist and do not depend on each other in a cycle
+func LinkProcessDependencies(contexts []*ExecutionContext) error {
+	x := map[string]*ExecutionContext{}
+	names := []string{}
+	edges := map[string][]string{}
+	for _, context := range contexts {
+		x[context.Process.Name] = context
+		names = append(names, context.Process.Name)
+	}
+
+	for _, context := range contexts {
+		for _, dependency := range context.Process.DependsOn {
+			target, exists := x[dependency.Process]
+			if !exists {
+				return errors.New("Specified dependency does not exist on " + context.Process.Name + ", Non existant dependency = " + dependency.Process)
+			}
+			switch dependency.GetCondition() {
+			case DependencyStarted, DependencyCompletedSuccessfully:
+			case DependencyHealthy:
+				if !target.Process.HealthCheck.Enabled() {
+					return fmt.Errorf("%s depends on %s being healthy but %s has no healthcheck", context.Process.Name, target.Process.Name, target.Process.Name)
+				}
+			default:
+				return fmt.Errorf("unknown dependency condition %q on %s -- started, healthy or completed_successfully supported", dependency.Condition, context.Process.Name)
+			}
+			edges[context.Process.Name] = append(edges[context.Process.Name], dependency.Process)
+		}
+	}
+
+	if cycle := findCycle(names, edges); cycle != nil {
+		return errors.New("Circular dependency detected: " + strings.Join(cycle, " -> "))
+	}
+
+	for _, context := range contexts {
+		context.dependencies = []*dependencyMonitor{}
+		for _, dependency := range context.Process.DependsOn {
+			monitor := x[dependency.Process].createDependencyMonitor(dependency.GetCondition())
+			context.dependencies = append(context.dependencies, monitor)
+		}
+	}
+
+	return nil
+}
+
+// Blocks until every dependency is ready. Returns false if the process should not start,
+// either because it was buzzkilled or a dependency can no longer become ready. Triggers received while waiting are ignored
+func (e *ExecutionContext) waitForDependencies(exitNotifier chan bool, triggers chan TriggerMessage) bool {
+	if len(e.dependencies) == 0 {
+		return true
+	}
+	e.setProcessStatus(ProcessStatusWaitingDependencies)
+
+	for _, dependency := range e.dependencies {
+		e.infoWriter.Printf("Waiting for %s to be %s", dependency.name, strings.ReplaceAll(string(dependency.condition), "_", " "))
+	waitLoop:
+		for {
+			select {
+			case <-dependency.ready:
+				break waitLoop
+			case <-dependency.unreachable:
+				e.errorWriter.Printf("%s exited without being %s, not starting", dependency.name, strings.ReplaceAll(string(dependency.condition), "_", " "))
+				return false
+			case <-exitNotifier:
+				e.infoWriter.Printf("Recieved buzzkill command")
+				return false
+			case message := <-triggers:
+				e.infoWriter.Printf("Waiting for dependencies, ignoring trigger - %s", message.Message)
+			}
+		}
+	}
+	return true
+}
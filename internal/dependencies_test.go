@@ -0,0 +1,57 @@
+package pp
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that cycles are found with their full path
+func TestFindCycle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		nodes    []string
+		edges    map[string][]string
+		expected []string
+	}{
+		{"no edges", []string{"a", "b"}, map[string][]string{}, nil},
+		{"chain", []string{"a", "b", "c"}, map[string][]string{"a": {"b"}, "b": {"c"}}, nil},
+		{"diamond", []string{"a", "b", "c", "d"}, map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, nil},
+		{"self", []string{"a"}, map[string][]string{"a": {"a"}}, []string{"a", "a"}},
+		{"mutual", []string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}}, []string{"a", "b", "a"}},
+		{"three", []string{"a", "b", "c"}, map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, []string{"a", "b", "c", "a"}},
+		{"cycle after entry", []string{"a", "b", "c", "d"}, map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"b"}}, []string{"b", "c", "d", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, findCycle(tt.nodes, tt.edges))
+		})
+	}
+}
+
+// Ensure that processes are ordered after their dependencies
+func TestStartupOrder(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	processes := []Process{
+		{Name: "web", DependsOn: []Dependency{{Process: "api"}}},
+		{Name: "api", DependsOn: []Dependency{{Process: "db"}}},
+		{Name: "db"},
+		{Name: "tailwind"},
+	}
+	contexts := []*ExecutionContext{}
+	for i := range processes {
+		contexts = append(contexts, processes[i].CreateContext(&wg))
+	}
+
+	names := []string{}
+	for _, context := range startupOrder(contexts) {
+		names = append(names, context.Process.Name)
+	}
+	assert.Equal(t, []string{"db", "api", "web", "tailwind"}, names)
+}
@@ -0,0 +1,120 @@
+package pp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Parses a dotenv formatted stream into a map of key value pairs
+// Supports comments, "export" prefixes, and single/double quoted values
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid line %d, expected KEY=VALUE", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid key on line %d: %q", lineNumber, key)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			// Strip inline comments on unquoted values
+			if index := strings.Index(value, " #"); index >= 0 {
+				value = strings.TrimSpace(value[:index])
+			}
+		}
+
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Reads and parses a dotenv file
+func loadEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values, err := parseDotenv(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse env file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Builds the environment for the process. Later sources override earlier ones:
+// process-party's environment, config env_file, config env, process env_file, process env
+func (p *Process) Environment() ([]string, error) {
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if key == "" {
+			continue
+		}
+		env[key] = value
+	}
+
+	layers := []struct {
+		files  []string
+		values map[string]string
+	}{
+		{p.GlobalEnvFiles, p.GlobalEnv},
+		{p.EnvFiles, p.Env},
+	}
+
+	for _, layer := range layers {
+		for _, path := range layer.files {
+			values, err := loadEnvFile(path)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range values {
+				env[key] = value
+			}
+		}
+		for key, value := range layer.values {
+			env[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key+"="+env[key])
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package pp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that dotenv files are parsed correctly
+func TestParseDotenv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		errors   bool
+	}{
+		{"basic", "KEY=value", map[string]string{"KEY": "value"}, false},
+		{"comments and blank lines", "# comment\n\nKEY=value\n", map[string]string{"KEY": "value"}, false},
+		{"export prefix", "export KEY=value", map[string]string{"KEY": "value"}, false},
+		{"double quotes", `KEY="hello\nworld"`, map[string]string{"KEY": "hello\nworld"}, false},
+		{"single quotes", `KEY='hello\nworld'`, map[string]string{"KEY": `hello\nworld`}, false},
+		{"inline comment", "KEY=value # comment", map[string]string{"KEY": "value"}, false},
+		{"hash in quotes", `KEY="value # not a comment"`, map[string]string{"KEY": "value # not a comment"}, false},
+		{"equals in value", "URL=postgres://u:p@host/db?a=b", map[string]string{"URL": "postgres://u:p@host/db?a=b"}, false},
+		{"empty value", "KEY=", map[string]string{"KEY": ""}, false},
+		{"missing equals", "KEY", nil, true},
+		{"empty key", "=value", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := parseDotenv(strings.NewReader(tt.input))
+			if tt.errors {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+// Ensure that environment sources are applied in the documented precedence order
+func TestEnvironmentPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	globalFile := filepath.Join(dir, "global.env")
+	processFile := filepath.Join(dir, "process.env")
+	err := os.WriteFile(globalFile, []byte("A=global-file\nB=global-file\nC=global-file\nD=global-file\n"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(processFile, []byte("B=process-file\nC=process-file\nD=process-file\n"), 0644)
+	assert.NoError(t, err)
+
+	t.Setenv("PP_ENV_TEST_OS", "os")
+	t.Setenv("A", "os")
+
+	process := Process{
+		GlobalEnvFiles: []string{globalFile},
+		GlobalEnv:      map[string]string{"B": "global-env", "C": "global-env"},
+		EnvFiles:       []string{processFile},
+		Env:            map[string]string{"D": "process-env"},
+	}
+
+	env, err := process.Environment()
+	assert.NoError(t, err)
+
+	values := map[string]string{}
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		values[key] = value
+	}
+
+	assert.Equal(t, "os", values["PP_ENV_TEST_OS"], "Should inherit the environment")
+	assert.Equal(t, "global-file", values["A"], "Config env files override the environment")
+	assert.Equal(t, "process-file", values["B"], "Process env files override config env")
+	assert.Equal(t, "process-file", values["C"], "Process env files override config env")
+	assert.Equal(t, "process-env", values["D"], "Process env overrides everything")
+
+	process.EnvFiles = []string{filepath.Join(dir, "missing.env")}
+	_, err = process.Environment()
+	assert.Error(t, err, "Missing env files should error")
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,8 +22,8 @@ type (
 		cmd                      *exec.Cmd
 		infoWriter               *customWriter
 		errorWriter              *customWriter
-		readPipe                 *io.PipeReader //
-		writePipe                *io.PipeWriter
+		stdinPipe                io.WriteCloser // Standard input of the current command
+		processDone              chan struct{}  // Closed when the current command exits
 		Process                  *Process
 		wg                       *sync.WaitGroup
 		exitEvent                ExecutionExitEvent
@@ -30,13 +31,38 @@ type (
 		internalExitNotifiers    []chan bool          // All related internal goroutines should lock onto this notifier to exit when the process is killed
 		externalProcessNotifiers []chan ProcessStatus // Allow external processes to hook into process notifications (running, failed, exited, restarting etc,)
 		executionExitNotifier    chan bool            // Used to have a single exit notifier for multiple creations of an excecutioion
-		triggers                 []chan string
+		triggers                 []chan TriggerMessage
+		startedBy                TriggerMessage // Trigger that started the current run, empty if it was not triggered
+		history                  *logHistory    // Recent output of the process
+		metrics                  *processMetrics
+		logFile                  *logFileWriter // Log file the output is also written to (optional)
 		stdIn                    chan string
-		exitCode                 int
+		ExitCode                 int       // Exit code of the last execution (-1 if it was killed or did not start)
+		exitTime                 time.Time // When the last execution ended
 		executionMutex           *sync.RWMutex
 		Status                   ProcessStatus
 		restartCounter           int
+		backoffAttempt           int // Restarts since the process last ran stable, used for the backoff delay
+		crashCounter             int // Consecutive quick failures, reset by a success or a failure after a long uptime
 		internalExit             atomic.Bool
+		started                  atomic.Bool
+		restartRequested         atomic.Bool          // Set when the running command is stopped to be restarted
+		stopRequested            atomic.Bool          // Set when the running command is stopped until it is started again
+		restarts                 atomic.Int32         // Total restarts of the process, including requested restarts
+		pid                      atomic.Int64         // PID of the last execution, read by the JSON output
+		control                  chan controlRequest  // Commands controlling the process (start, stop, restart, etc.)
+		interruptDelay           chan struct{}        // Stops waiting for the restart delay when the process is stopped
+		dependencies             []*dependencyMonitor // Processes that have to be ready before starting
+		executions               sync.WaitGroup       // Executions started by triggers
+		done                     chan struct{}        // Closed once the context has completely ended
+		party                    *party               // Contexts that run together, ended processes stay idle until the party ends
+	}
+
+	// Message of a trigger firing and the kind of trigger that fired
+	TriggerMessage struct {
+		Kind    string
+		Message string
+		Changes []FileChange // Files that changed since the last run of fs triggers
 	}
 )
 
@@ -53,27 +79,65 @@ const (
 	ProcessStatusWaitingTrigger
 	ProcessStatusExited
 	ProcessStatusFailed
+	// New statuses are appended so the values of existing statuses do not change
+	ProcessStatusHealthy
+	ProcessStatusUnhealthy
+	ProcessStatusWaitingDependencies
+	ProcessStatusCrashLooping
+	ProcessStatusStopped // Last status, metrics report every status up to it
 )
 
+// Returns the executions current status
+func (c *ExecutionContext) GetStatus() ProcessStatus {
+	c.executionMutex.RLock()
+	defer c.executionMutex.RUnlock()
+	return c.Status
+}
+
 // Returns the executions current status as a string
 func (c *ExecutionContext) GetStatusAsStr() string {
-	switch c.Status {
+	return c.GetStatus().String()
+}
+
+// Returns the status as a string
+func (s ProcessStatus) String() string {
+	switch s {
 	case ProcessStatusNotStarted:
 		return "Not started"
 	case ProcessStatusRunning:
 		return "Running"
+	case ProcessStatusHealthy:
+		return "Healthy"
+	case ProcessStatusUnhealthy:
+		return "Unhealthy"
 	case ProcessStatusExited:
 		return "Exited"
 	case ProcessStatusFailed:
 		return "Failed"
 	case ProcessStatusWaitingTrigger:
 		return "Waiting for trigger"
+	case ProcessStatusWaitingDependencies:
+		return "Waiting for dependencies"
 	case ProcessStatusRestarting:
 		return "Restarting"
+	case ProcessStatusCrashLooping:
+		return "Crash looping"
+	case ProcessStatusStopped:
+		return "Stopped"
 	}
 	return "Unknown"
 }
 
+// Returns the status as a metric label value, e.g. crash_looping
+func (s ProcessStatus) metricName() string {
+	return strings.ReplaceAll(strings.ToLower(s.String()), " ", "_")
+}
+
+// Returns true if the process is currently running, regardless of its health
+func (s ProcessStatus) IsRunning() bool {
+	return s == ProcessStatusRunning || s == ProcessStatusHealthy || s == ProcessStatusUnhealthy
+}
+
 // Creates an execution context
 func (p *Process) CreateContext(wg *sync.WaitGroup) *ExecutionContext {
 	context := &ExecutionContext{
@@ -83,21 +147,38 @@ func (p *Process) CreateContext(wg *sync.WaitGroup) *ExecutionContext {
 		externalProcessNotifiers: make([]chan ProcessStatus, 0),
 		stdIn:                    make(chan string, 10),
 		buzzkillEmitters:         make([]chan bool, 0),
-		triggers:                 make([]chan string, 0),
+		triggers:                 make([]chan TriggerMessage, 0),
 		executionMutex:           &sync.RWMutex{},
+		done:                     make(chan struct{}),
+		control:                  make(chan controlRequest),
+		interruptDelay:           make(chan struct{}, 1),
+		history:                  newLogHistory(ApiLogLines),
+		metrics:                  newProcessMetrics(),
 	}
 
 	// Write into the command
-	context.infoWriter = &customWriter{w: os.Stdout, severity: "info", process: context.Process}   // Write info out
-	context.errorWriter = &customWriter{w: os.Stdout, severity: "error", process: context.Process} // Write errors out
-	// Set IO
-	context.readPipe, context.writePipe = io.Pipe()
-
+	context.infoWriter = &customWriter{w: os.Stdout, severity: "info", process: context.Process, history: context.history, lines: &context.metrics.stdoutLines, assembler: &lineAssembler{}}   // Write info out
+	context.errorWriter = &customWriter{w: os.Stdout, severity: "error", process: context.Process, history: context.history, lines: &context.metrics.stderrLines, assembler: &lineAssembler{}} // Write errors out
 	// Internal buzzkill
 	context.executionExitNotifier = context.getInternalExitNotifier()
+	joinParty(context)
 	return context
 }
 
+// Sends the output and messages of the process to the writers instead of the standard output, without prefixes.
+// Has to be called before the context is started
+func (e *ExecutionContext) SetOutput(info io.Writer, errors io.Writer) {
+	e.infoWriter = &customWriter{w: info, severity: "info", process: e.Process, noPrefix: true, history: e.history, lines: &e.metrics.stdoutLines, assembler: &lineAssembler{}}
+	e.errorWriter = &customWriter{w: errors, severity: "error", process: e.Process, noPrefix: true, history: e.history, lines: &e.metrics.stderrLines, assembler: &lineAssembler{}}
+}
+
+// Writes the output and messages of the process to the writer as JSON objects, one per line.
+// Has to be called before the context is started
+func (e *ExecutionContext) SetJsonOutput(w io.Writer) {
+	e.infoWriter = &customWriter{w: w, severity: "info", process: e.Process, noPrefix: true, json: true, pid: &e.pid, history: e.history, lines: &e.metrics.stdoutLines, assembler: &lineAssembler{}}
+	e.errorWriter = &customWriter{w: w, severity: "error", process: e.Process, noPrefix: true, json: true, pid: &e.pid, history: e.history, lines: &e.metrics.stderrLines, assembler: &lineAssembler{}}
+}
+
 // Returns a listening channel to listen for a buzzkill event comming FROM the process
 // This channel can close so be sure to check with _,ok:= <- emitted
 func (e *ExecutionContext) GetBuzkillEmitter() chan bool {
@@ -157,16 +238,14 @@ func (e *ExecutionContext) closeChannels() {
 		}
 	}
 
-	if e.stdIn != nil {
-		close(e.stdIn)
-	}
-	e.stdIn = nil
+	// The standard input is never closed as Write could still be sending to it, Write stops once the context is done
 
 	// Triggers should close themselves on internal exit emitted (dont close here)
 }
 
 // Emits the buzzkill command FROM INSIDE the process
 func (e *ExecutionContext) emitBuzkill() {
+	e.metrics.buzzkills.Add(1)
 	e.executionMutex.RLock()
 	// Send external notifications
 	for _, channel := range e.buzzkillEmitters {
@@ -183,6 +262,8 @@ func (e *ExecutionContext) emitBuzkill() {
 
 // Tell all goroutines related to this process to stop and exit
 func (e *ExecutionContext) BuzzkillProcess() {
+	// Listen before notifying so that the exit can't be missed
+	statusChannel := e.GetProcessNotificationChannel()
 	e.executionMutex.RLock()
 	for i := range e.internalExitNotifiers {
 		if e.internalExitNotifiers[i] != nil {
@@ -193,9 +274,7 @@ func (e *ExecutionContext) BuzzkillProcess() {
 
 	// Wait for the status to change to exited before closing all channels
 	go func() {
-		statusChannel := e.GetProcessNotificationChannel()
-		for {
-			status := <-statusChannel
+		for status := range statusChannel {
 			if status == ProcessStatusExited {
 				break
 			}
@@ -206,9 +285,14 @@ func (e *ExecutionContext) BuzzkillProcess() {
 
 // Updates the process status and sends external notifications of said status
 func (e *ExecutionContext) setProcessStatus(status ProcessStatus) {
+	e.executionMutex.Lock()
+	e.Status = status
+	e.executionMutex.Unlock()
+	e.metrics.statusChanged(status)
+
+	// Notifiers are closed under the write lock, hold the read lock while sending
 	e.executionMutex.RLock()
 	defer e.executionMutex.RUnlock()
-	e.Status = status
 	for _, channel := range e.externalProcessNotifiers {
 		if channel != nil {
 			channel <- status
@@ -217,10 +301,86 @@ func (e *ExecutionContext) setProcessStatus(status ProcessStatus) {
 
 }
 
-// Writes to the executing execution context
-func (e *ExecutionContext) Write(input string) {
-	if e.stdIn != nil {
-		e.stdIn <- input
+// Returns the exit code of the last execution (-1 if it was killed or did not start)
+func (e *ExecutionContext) GetExitCode() int {
+	e.executionMutex.RLock()
+	defer e.executionMutex.RUnlock()
+	return e.ExitCode
+}
+
+func (e *ExecutionContext) setExitCode(code int) {
+	e.executionMutex.Lock()
+	defer e.executionMutex.Unlock()
+	e.ExitCode = code
+}
+
+// Returns how the last execution ended
+func (e *ExecutionContext) getExitEvent() ExecutionExitEvent {
+	e.executionMutex.RLock()
+	defer e.executionMutex.RUnlock()
+	return e.exitEvent
+}
+
+func (e *ExecutionContext) setExitEvent(event ExecutionExitEvent) {
+	e.executionMutex.Lock()
+	defer e.executionMutex.Unlock()
+	e.exitEvent = event
+}
+
+// Returns a channel that is closed once the context has completely ended
+func (e *ExecutionContext) Done() <-chan struct{} {
+	return e.done
+}
+
+// Immediately kills the running command without waiting for it to stop gracefully
+func (e *ExecutionContext) Kill() {
+	e.executionMutex.RLock()
+	cmd := e.cmd
+	processDone := e.processDone
+	e.executionMutex.RUnlock()
+	if cmd == nil || cmd.Process == nil || processDone == nil {
+		return
+	}
+	select {
+	case <-processDone:
+	default:
+		if killProcessGroup(cmd.Process.Pid) != nil {
+			cmd.Process.Kill()
+		}
+	}
+}
+
+// Gracefully stops the contexts one at a time in reverse order, waiting for each to end before stopping the next.
+// Processes are always stopped before the processes they depend on
+func Shutdown(contexts []*ExecutionContext) {
+	contexts = startupOrder(contexts)
+	for i := len(contexts) - 1; i >= 0; i-- {
+		context := contexts[i]
+		if !context.started.Load() {
+			continue
+		}
+		select {
+		case <-context.done:
+			continue
+		default:
+		}
+		context.BuzzkillProcess()
+		<-context.done
+	}
+}
+
+// Writes the input as a line to the standard input of the running process, returns ErrProcessNotRunning once the context has ended
+func (e *ExecutionContext) Write(input string) error {
+	select {
+	case <-e.done:
+		return ErrProcessNotRunning
+	default:
+	}
+	select {
+	case e.stdIn <- input:
+		return nil
+	case <-e.done:
+		return ErrProcessNotRunning
 	}
 }
 
@@ -260,45 +420,92 @@ func (config *Config) GenerateRunTaskContexts(wg *sync.WaitGroup) []*ExecutionCo
 
 	// Apply process triggers to contexts here
 
+	joinParty(contexts...)
 	return contexts
 }
 
-// Handles how the execution context behaves on exit, depending on exit behaviour
-func (e *ExecutionContext) handleProcessExit() {
+// Handles how the execution context behaves on exit, depending on exit behaviour. Returns true if the process should be restarted
+func (e *ExecutionContext) handleProcessExit(uptime time.Duration) bool {
+	// Restarts requested while running (e.g. unhealthy processes) do not count as restart attempts
+	if e.restartRequested.Swap(false) && e.getExitEvent() != ExitEventBuzzkilled {
+		e.setProcessStatus(ProcessStatusRestarting)
+		return true
+	}
+	// Stopped processes wait to be started again
+	if e.stopRequested.Load() {
+		return false
+	}
+
+	status := e.GetStatus()
+	failed := status == ProcessStatusFailed || status == ProcessStatusNotStarted
 	exitCommand := ExitCommandWait
-	if e.exitEvent != ExitEventBuzzkilled {
-		if e.Status == ProcessStatusFailed || e.Status == ProcessStatusNotStarted {
+	exitCodeCommand := false // Exit code restarts do not use up restart attempts
+	if e.getExitEvent() != ExitEventBuzzkilled {
+		if failed {
 			exitCommand = e.Process.OnFailure
 		} else {
 			exitCommand = e.Process.OnComplete
 		}
+		// Exit code actions only apply to processes that exited on their own
+		if !e.internalExit.Load() {
+			if command, ok := e.Process.GetExitCodeCommand(e.GetExitCode()); ok {
+				exitCommand = command
+				exitCodeCommand = true
+			}
+		}
+	}
+
+	// Runs that stayed up for the restart window are stable, earlier restarts are forgotten
+	if window := e.Process.GetRestartWindow(); window > 0 && uptime >= window {
+		e.restartCounter = 0
+		e.backoffAttempt = 0
+		e.crashCounter = 0
+	}
+	if failed && e.Process.isQuickFailure(uptime) {
+		e.crashCounter++
+	} else {
+		e.crashCounter = 0
 	}
 
 	switch exitCommand {
 	case ExitCommandBuzzkill:
-		e.errorWriter.Write([]byte("Buzzkilling other processes"))
-		e.exitEvent = ExitEventBuzzkiller
+		e.errorWriter.Printf("Buzzkilling other processes")
+		e.setExitEvent(ExitEventBuzzkiller)
 		e.emitBuzkill()
 		e.BuzzkillProcess()
 
 	case ExitCommandRestart:
 
-		e.restartCounter++
-
-		if e.restartCounter >= e.Process.RestartAttempts && e.Process.RestartAttempts >= 0 {
-			e.infoWriter.Printf("No restart attempts left, exiting")
-			return
+		if !exitCodeCommand {
+			e.restartCounter++
+			if e.restartCounter >= e.Process.RestartAttempts && e.Process.RestartAttempts >= 0 {
+				e.infoWriter.Printf("No restart attempts left, exiting")
+				return false
+			}
 		}
 
-		e.setProcessStatus(ProcessStatusRestarting)
-		if e.Process.RestartAttempts > 0 {
-			e.infoWriter.Printf("Process exited - Restarting, %d second restart delay, %d attempts remaining", e.Process.RestartDelay, e.Process.RestartAttempts-e.restartCounter)
+		delay := e.Process.GetRestartDelay(e.backoffAttempt)
+		e.backoffAttempt++
+		if e.crashCounter >= CrashLoopThreshold {
+			e.setProcessStatus(ProcessStatusCrashLooping)
+			e.errorWriter.Eventf(OutputLine{Event: EventRestarting}, "Process is crash looping, failed %d times in a row - Restarting, %s restart delay", e.crashCounter, delay.Round(time.Millisecond))
+		} else {
+			e.setProcessStatus(ProcessStatusRestarting)
+			if e.Process.RestartAttempts > 0 && !exitCodeCommand {
+				e.infoWriter.Eventf(OutputLine{Event: EventRestarting}, "Process exited - Restarting, %s restart delay, %d attempts remaining", delay.Round(time.Millisecond), e.Process.RestartAttempts-e.restartCounter)
+			} else {
+				e.infoWriter.Eventf(OutputLine{Event: EventRestarting}, "Process exited - Restarting, %s restart delay", delay.Round(time.Millisecond))
+			}
 		}
-		if e.Process.RestartDelay > 0 {
-			time.Sleep(time.Duration(e.Process.RestartDelay) * time.Second)
+		if !e.waitDelay(delay) {
+			if e.stopRequested.Load() {
+				return false
+			}
+			e.infoWriter.Printf("Recieved buzzkill command")
+			e.setExitEvent(ExitEventBuzzkilled)
+			return false
 		}
-		// Recursive call
-		e.execute(nil, nil)
+		return true
 
 	case ExitCommandWait:
 		if len(e.triggers) == 0 {
@@ -306,211 +513,400 @@ func (e *ExecutionContext) handleProcessExit() {
 		}
 	}
 
-	e.setProcessStatus(ProcessStatusExited)
+	return false
+}
+
+// Runs the process until it exits without being restarted. Only the first execution sends on started
+func (c *ExecutionContext) run(started chan bool) {
+	// Clear interrupts left by stopping the previous run
+	select {
+	case <-c.interruptDelay:
+	default:
+	}
+	for c.execute(started) {
+		started = nil
+		c.restarts.Add(1)
+	}
+	if c.stopRequested.Load() {
+		c.setProcessStatus(ProcessStatusStopped)
+		return
+	}
+	c.setProcessStatus(ProcessStatusExited)
+}
+
+// Sends output to the log matcher as well as the target, the null device only has the matcher
+func outputWithMatcher(target io.Writer, matcher *logStreamMatcher) io.Writer {
+	if target == nil {
+		return matcher
+	}
+	return io.MultiWriter(matcher, target)
+}
+
+// Creates the command for the process, wrapping it in the shell when shell mode is enabled.
+// {{changed_files}} is replaced by the files changed since the last run of an fs trigger
+func (p *Process) createCommand(trigger TriggerMessage) *exec.Cmd {
+	files := trigger.changedFiles()
+	args := expandChangedFiles(p.Args, files)
+	if !p.UseShell() {
+		return exec.Command(p.Command, args...)
+	}
+	commandLine := strings.ReplaceAll(p.Command, ChangedFilesTemplate, joinShellArgs(files))
+	if len(args) > 0 {
+		commandLine += " " + joinShellArgs(args)
+	}
+	shell, flag := defaultShell()
+	return exec.Command(shell, flag, commandLine)
 }
 
-// Actual execution of the desired process/execution context.
-func (c *ExecutionContext) execute(started chan bool, ended chan bool) {
+// Waits for the delay, returns false if the process was buzzkilled or stopped while waiting
+func (c *ExecutionContext) waitDelay(delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.executionExitNotifier:
+		return false
+	case <-c.interruptDelay:
+		return false
+	}
+}
+
+// Actual execution of the desired process/execution context. Sends on started once the process started or failed to start.
+// The execution is driven entirely by events: the process exiting, standard input, and buzzkills.
+// Returns true if the process should be restarted
+func (c *ExecutionContext) execute(started chan bool) bool {
 	c.setProcessStatus(ProcessStatusNotStarted)
 	c.internalExit.Store(false)
 
+	// Wait for the start delay
+	c.infoWriter.Printf("Starting process - %d second delay", c.Process.Delay)
+	if !c.waitDelay(time.Duration(c.Process.Delay) * time.Second) {
+		c.infoWriter.Printf("Recieved buzzkill command")
+		c.setExitEvent(ExitEventBuzzkilled)
+		if started != nil {
+			started <- true
+		}
+		return c.handleProcessExit(0)
+	}
+
 	c.executionMutex.Lock()
+	c.restartRequested.Store(false)
 	// Create command
-	c.cmd = exec.Command(c.Process.Command, c.Process.Args...)
-	c.cmd.Env = os.Environ() // Set the full environment, including PATH
+	c.cmd = c.Process.createCommand(c.startedBy)
+	// Set the full environment, including PATH, with the configured variables and the trigger applied
+	env, envErr := c.Process.Environment()
+	c.cmd.Env = append(env, c.startedBy.environment()...)
+	c.cmd.Dir = c.Process.Cwd
+	setProcessGroup(c.cmd)
 	// Create IO
 	c.cmd.Stdout = c.infoWriter
 	c.cmd.Stderr = c.errorWriter
-	c.cmd.Stdin = c.readPipe
-	displayedPid := false // Simple bool to show and set boolean at the start of the process
-	// Wait for the start delay
-	c.infoWriter.Printf("Starting process - %d second delay", c.Process.Delay)
-	if c.Process.Delay > 0 {
-		time.Sleep(time.Duration(c.Process.Delay) * time.Second)
+	// Watch the output for the health check log pattern
+	matcher := c.Process.HealthCheck.createLogMatcher()
+	if matcher != nil {
+		c.cmd.Stdout = outputWithMatcher(c.cmd.Stdout, matcher.stream())
+		c.cmd.Stderr = outputWithMatcher(c.cmd.Stderr, matcher.stream())
 	}
-	c.exitCode = -1
+	// Stop waiting for output once the process exits and leaves its pipes with a child process
+	c.cmd.WaitDelay = time.Second
+	stdinPipe, pipeErr := c.cmd.StdinPipe()
+	c.stdinPipe = stdinPipe
+	c.ExitCode = -1
 	// Start the command
-	startErr := c.cmd.Start()
+	startErr := envErr
+	if startErr == nil {
+		startErr = pipeErr
+	}
+	if startErr == nil {
+		startErr = c.cmd.Start()
+	}
+	processDone := make(chan struct{})
+	c.processDone = processDone
 	c.executionMutex.Unlock()
-	processDone := make(chan struct{}, 1)
 	// Go wait somewhere else lamo (*insert you cant sit with us meme*)
 	go func() {
 		defer close(processDone)
 		c.cmd.Wait()
 	}()
 
+	// Health checks stop before the exit is handled so they can't overwrite the exit status
+	stopHealthCheck := func() {}
+	startTime := time.Now()
+	// Display the PID on the first line
+	if startErr == nil {
+		c.executionMutex.Lock()
+		c.Process.Pid = fmt.Sprintf("%d", c.cmd.Process.Pid)
+		c.pid.Store(int64(c.cmd.Process.Pid))
+		c.executionMutex.Unlock()
+
+		c.setProcessStatus(ProcessStatusRunning)
+		c.infoWriter.Eventf(OutputLine{Event: EventStarted}, "PID = %s", c.Process.Pid)
+		stopHealthCheck = c.startHealthCheck(matcher)
+		// Stream the initial start stream value once
+		if c.Process.StartStream != "" {
+			c.stdinPipe.Write([]byte(c.Process.StartStream + "\n"))
+			c.Process.StartStream = ""
+		}
+		// Send started signal
+		if started != nil {
+			started <- true
+		}
+	}
+
+	// Children left running by a process that exited on its own are only stopped if it is restarted
+	childrenLeft := false
 commandLoop:
 	for {
 		select {
 		case <-processDone:
-			break commandLoop
-		case value := <-c.stdIn: // Received std in
-			if c.cmd.Process != nil && c.cmd.ProcessState.ExitCode() < 0 && startErr != nil {
-				c.writePipe.Write([]byte(value + "\n"))
-			}
-
-		case <-c.executionExitNotifier: // Recieved buzzkill
-			c.exitEvent = ExitEventBuzzkilled
-			c.infoWriter.Printf("Recieved buzzkill command")
-			c.killExecution()
-			break commandLoop
-
-		default:
-
-			// Display the PID on the first line
-			if !displayedPid && c.cmd.Process != nil {
-				c.executionMutex.Lock()
-				c.Process.Pid = fmt.Sprintf("%d", c.cmd.Process.Pid)
-				c.executionMutex.Unlock()
-
-				c.setProcessStatus(ProcessStatusRunning)
-				c.infoWriter.Printf("PID = %s", c.Process.Pid)
-				displayedPid = true
-				// Send started signal
+			stopHealthCheck()
+			// Partial lines are written before the exit is reported
+			c.infoWriter.flush()
+			c.errorWriter.flush()
+			// Handle the process exiting
+			if startErr != nil {
+				exitCode := c.ExitCode
+				c.errorWriter.Printf("Failed to start")
+				c.errorWriter.Eventf(OutputLine{Event: EventExited, ExitCode: &exitCode}, "%s", startErr.Error())
+				c.setProcessStatus(ProcessStatusFailed)
+				c.setExitEvent(ExitEventInternal)
+				// Unblock trigger runtime if process failed to start
 				if started != nil {
 					started <- true
 				}
-			}
-
-			// Handle triggers killing the process
-			if c.internalExit.Load() {
-				c.infoWriter.Printf("Trigger cancelled execution")
-				c.exitEvent = ExitEventInternal
 				break commandLoop
 			}
 
-			// Handle the process exiting
-			if (c.cmd.Process != nil && c.cmd.ProcessState != nil && c.cmd.ProcessState.ExitCode() >= 0) ||
-				startErr != nil ||
-				c.Status == ProcessStatusFailed ||
-				c.Status == ProcessStatusExited {
-				if c.cmd.ProcessState.ExitCode() == 0 {
-					c.infoWriter.Printf("Detected Process exit")
-					c.exitEvent = ExitEventInternal
-				} else if c.cmd.ProcessState.ExitCode() > 0 && !c.internalExit.Load() {
-					c.errorWriter.Write([]byte("Detected Process failure"))
-					c.setProcessStatus(ProcessStatusFailed)
-				} else if startErr != nil {
-					c.errorWriter.Write([]byte("Failed to start"))
-					c.errorWriter.Write([]byte(startErr.Error()))
-					c.setProcessStatus(ProcessStatusFailed)
-					c.exitEvent = ExitEventInternal
-					// Unblock trigger runtime if process failed to start
-					if started != nil {
-						started <- true
-					}
+			exitCode := c.cmd.ProcessState.ExitCode()
+			c.setExitCode(exitCode)
+			exited := OutputLine{Event: EventExited, ExitCode: &exitCode}
+			// Make sure no children outlive a process stopped by process party
+			if c.restartRequested.Load() || c.stopRequested.Load() || c.internalExit.Load() {
+				if err := c.cleanupProcessGroup(c.cmd.Process.Pid, processDone); err != nil {
+					c.errorWriter.Printf("Could not stop child processes: %s", err.Error())
 				}
-				c.exitCode = c.cmd.ProcessState.ExitCode()
-				break commandLoop
+			} else {
+				childrenLeft = true
+			}
+			if c.restartRequested.Load() {
+				c.infoWriter.Eventf(exited, "Process stopped for restart")
+				c.setExitEvent(ExitEventInternal)
+			} else if c.stopRequested.Load() {
+				c.infoWriter.Eventf(exited, "Process stopped")
+				c.setExitEvent(ExitEventInternal)
+			} else if c.internalExit.Load() {
+				// Handle triggers killing the process
+				c.infoWriter.Eventf(exited, "Trigger cancelled execution")
+				c.setExitEvent(ExitEventInternal)
+			} else if c.Process.IsSuccessExitCode(exitCode) {
+				c.infoWriter.Eventf(exited, "Detected Process exit")
+				c.setExitEvent(ExitEventInternal)
+			} else {
+				c.errorWriter.Eventf(exited, "Detected Process failure - %s", c.cmd.ProcessState.String())
+				c.setProcessStatus(ProcessStatusFailed)
 			}
+			break commandLoop
 
-			// Stream the initial start stream values and set it to an empty string
-			if c.Process.StartStream != "" {
-				c.writePipe.Write([]byte(c.Process.StartStream + "\n"))
-				c.Process.StartStream = ""
+		case value := <-c.stdIn: // Received std in
+			if startErr == nil {
+				c.stdinPipe.Write([]byte(value + "\n"))
+			}
+
+		case <-c.executionExitNotifier: // Recieved buzzkill
+			stopHealthCheck()
+			c.setExitEvent(ExitEventBuzzkilled)
+			c.infoWriter.Printf("Recieved buzzkill command")
+			err := c.killExecution()
+			if err != nil {
+				c.errorWriter.Printf("An error occurred when stopping the process with PID %s: %s", c.Process.Pid, err.Error())
 			}
+			if c.cmd.ProcessState != nil {
+				c.setExitCode(c.cmd.ProcessState.ExitCode())
+			}
+			exitCode := c.GetExitCode()
+			c.infoWriter.Eventf(OutputLine{Event: EventExited, ExitCode: &exitCode}, "Process stopped by buzzkill")
+			break commandLoop
 
-			// Don't spin too hard
-			time.Sleep(10 * time.Millisecond)
 		}
 	}
 
-	// The process exits so quick we need to delay to ensure that the buzzkill command is sent
-	c.handleProcessExit()
-	if ended != nil {
-		select {
-		case ended <- true:
-			break
-		case <-time.After(time.Duration(100) * time.Millisecond):
-			break
+	stopHealthCheck()
+	c.infoWriter.flush()
+	c.errorWriter.flush()
+	c.executionMutex.Lock()
+	c.exitTime = time.Now()
+	c.executionMutex.Unlock()
+	c.metrics.exited(c.ExitCode)
+	restart := c.handleProcessExit(c.exitTime.Sub(startTime))
+	if restart && childrenLeft {
+		if err := c.cleanupProcessGroup(c.cmd.Process.Pid, processDone); err != nil {
+			c.errorWriter.Printf("Could not stop child processes: %s", err.Error())
 		}
 	}
+	return restart
+}
+
+// Opens the log file of the process for the writers, failing to open it only stops the output from being written to it
+func (e *ExecutionContext) openLogFile() {
+	options := e.Process.GetLogFile()
+	if options.Path == "" {
+		return
+	}
+	file, err := openLogFile(options)
+	if err != nil {
+		e.errorWriter.Printf("Could not open the log file %s: %s", options.Path, err)
+		return
+	}
+	e.logFile = &logFileWriter{file: file, options: options}
+	e.infoWriter.file = e.logFile
+	e.errorWriter.file = e.logFile
 }
 
 // Cleanup operations on remaining channels
 func (e *ExecutionContext) end() {
-	if e.writePipe != nil {
-		e.writePipe.Close()
+	// Wait for executions started by triggers to finish
+	e.executions.Wait()
+	e.party.setIdle(e)
+	if e.logFile != nil {
+		e.logFile.file.release()
 	}
-	if e.readPipe != nil {
-		e.readPipe.Close()
-	}
-	// This unfortunately needs to be there to let things settle properly
-	time.Sleep(time.Millisecond * 100)
+	// Nothing is emitted after the context ends, listeners see their channels close
+	e.closeChannels()
+	close(e.done)
 	e.wg.Done()
 }
 
 // Starts execution of the process or monitoring for any trigger events to trigger the process. Exits with buzzkill
 func (e *ExecutionContext) Start() {
 	e.wg.Add(1)
+	e.started.Store(true)
+	e.openLogFile()
 	exitNotifier := e.getInternalExitNotifier()
 
 	go func() {
 		defer e.end()
 
-		if len(e.triggers) == 0 {
-			e.execute(nil, nil)
-			return
-		}
-
-		e.setProcessStatus(ProcessStatusWaitingTrigger)
-
 		// Start a goroutine for each trigger to forward messages
-		triggerChan := make(chan string)
+		triggerChan := make(chan TriggerMessage)
 		for _, trigger := range e.triggers {
-			go func(t chan string) {
+			go func(t chan TriggerMessage) {
 				for msg := range t {
+					e.metrics.triggers[msg.Kind].Add(1)
 					triggerChan <- msg
 				}
 			}(trigger)
 		}
 
-		started := make(chan bool)
-		ended := make(chan bool)
-		hasRun := false
-		if e.Process.Trigger.RunOnStart {
+		if !e.waitForDependencies(exitNotifier, triggerChan) {
+			e.setProcessStatus(ProcessStatusExited)
+			return
+		}
+
+		// The monitor is either idle (waiting for a trigger or stopped) or running an execution,
+		// in which case executionDone is closed once the execution ended. Processes without
+		// triggers that ended stay idle until they are started again or the party ends
+		var executionDone chan struct{}
+		stopped := false
+		ended := false
+		run := func(message TriggerMessage) {
+			e.startedBy = message
+			started := make(chan bool)
+			done := make(chan struct{})
+			e.executions.Add(1)
 			go func() {
-				e.execute(started, ended)
-				e.setProcessStatus(ProcessStatusWaitingTrigger)
+				defer e.executions.Done()
+				defer close(done)
+				e.run(started)
+				e.executionMutex.Lock()
+				e.Process.Pid = ""
+				e.executionMutex.Unlock()
+				if len(e.triggers) > 0 && !e.stopRequested.Load() {
+					e.setProcessStatus(ProcessStatusWaitingTrigger)
+				}
 			}()
-			// Block the thread until the process started/ended properly
+			// Block the thread until the process started properly
 			<-started
-			hasRun = true
+			stopped = false
+			executionDone = done
+		}
+
+		// Starts the process on a trigger, returns false if the monitor should exit
+		trigger := func(message TriggerMessage) bool {
+			if executionDone != nil {
+				e.infoWriter.Printf("Current status: %s", e.GetStatusAsStr())
+				if !e.Process.Trigger.EndOnNew {
+					e.errorWriter.Printf("Can't start process, process is already running")
+					return true
+				}
+				err := e.killExecution()
+				if err != nil {
+					e.errorWriter.Printf("An error occurred when stopping the process with PID %d: %s", e.pid.Load(), err.Error())
+				}
+				// Wait for the execution to end
+				<-executionDone
+				executionDone = nil
+			}
+			if e.getExitEvent() != ExitEventInternal {
+				return false
+			}
+			run(message)
+			return true
+		}
+
+		if len(e.triggers) > 0 {
+			e.setProcessStatus(ProcessStatusWaitingTrigger)
+		}
+		if len(e.triggers) == 0 || e.Process.Trigger.RunOnStart {
+			run(TriggerMessage{})
 		}
 
 	monitorLoop:
 		for {
 			select {
 			case message := <-triggerChan:
-				e.infoWriter.Printf("%s", message)
-				if e.Status != ProcessStatusWaitingTrigger {
-					e.infoWriter.Printf("Current status: %s", e.GetStatusAsStr())
-					if !e.Process.Trigger.EndOnNew {
-						e.errorWriter.Printf("Can't start process, process is already running")
-						break
-					}
+				e.infoWriter.Eventf(OutputLine{Event: EventTriggered, Trigger: message.Kind, Changes: message.Changes}, "%s", message.Message)
+				if stopped {
+					e.infoWriter.Printf("Process is stopped, ignoring trigger")
+					break
 				}
-				err := e.killExecution()
-				if err != nil {
-					e.errorWriter.Printf("An error occurred when stopping the process with PID %s: %s", e.Process.Pid, err.Error())
-				}
-				if e.exitEvent != ExitEventInternal {
+				if !trigger(message) {
 					break monitorLoop
 				}
 
-				if hasRun && e.Status < ProcessStatusWaitingTrigger {
-					// Wait to get the end signal
-					<-ended
+			case request := <-e.control:
+				if ended && (request.command == controlStart || request.command == controlRestart) {
+					if !e.party.wake(e) {
+						request.result <- ErrProcessEnded
+						break monitorLoop
+					}
+					ended = false
+				}
+				request.result <- e.handleControl(request.command, executionDone != nil)
+				switch request.command {
+				case controlStart, controlRestart:
+					if executionDone == nil {
+						run(TriggerMessage{})
+					}
+				case controlTrigger:
+					if !trigger(TriggerMessage{Kind: TriggerKindManual, Message: "Triggered manually"}) {
+						break monitorLoop
+					}
+				}
 
+			case <-executionDone:
+				executionDone = nil
+				stopped = e.stopRequested.Swap(false)
+				if !stopped && len(e.triggers) == 0 {
+					ended = true
+					e.party.setIdle(e)
 				}
-				go func() {
-					hasRun = true
-					e.execute(started, ended)
-					e.Process.Pid = ""
-					e.setProcessStatus(ProcessStatusWaitingTrigger)
-				}()
-				// Block the thread until the process started/ended properly
-				<-started
+
+			case <-e.party.ended:
+				break monitorLoop
 
 			case <-exitNotifier:
 				break monitorLoop
@@ -518,3 +914,38 @@ func (e *ExecutionContext) Start() {
 		}
 	}()
 }
+
+// Applies a control command to the running execution, returns an error if the command can't be applied
+func (e *ExecutionContext) handleControl(command controlCommand, running bool) error {
+	switch command {
+	case controlStart:
+		if running {
+			return ErrProcessRunning
+		}
+	case controlStop, controlKill:
+		if !running {
+			return ErrProcessNotRunning
+		}
+		e.infoWriter.Printf("Stopping process")
+		e.stopRequested.Store(true)
+		select {
+		case e.interruptDelay <- struct{}{}:
+		default:
+		}
+		if command == controlKill {
+			go e.Kill()
+		} else {
+			go e.killExecution()
+		}
+	case controlRestart:
+		if running {
+			e.infoWriter.Eventf(OutputLine{Event: EventRestarting}, "Restarting process")
+			e.restartRequested.Store(true)
+			go e.killExecution()
+		}
+	case controlTrigger:
+		e.metrics.triggers[TriggerKindManual].Add(1)
+		e.infoWriter.Eventf(OutputLine{Event: EventTriggered, Trigger: TriggerKindManual}, "Triggered manually")
+	}
+	return nil
+}
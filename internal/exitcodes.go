@@ -0,0 +1,32 @@
+package pp
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Returns true if the exit code means the process completed successfully, 0 always does
+func (p *Process) IsSuccessExitCode(code int) bool {
+	return code == 0 || slices.Contains(p.SuccessExitCodes, code)
+}
+
+// Returns the exit command for exit codes that are handled regardless of on_failure and on_complete
+func (p *Process) GetExitCodeCommand(code int) (ExitCommand, bool) {
+	if slices.Contains(p.BuzzkillOnExitCodes, code) {
+		return ExitCommandBuzzkill, true
+	}
+	if slices.Contains(p.RestartOnExitCodes, code) {
+		return ExitCommandRestart, true
+	}
+	return "", false
+}
+
+// Validates that no exit code is given conflicting actions
+func (p *Process) ValidateExitCodes() error {
+	for _, code := range p.BuzzkillOnExitCodes {
+		if slices.Contains(p.RestartOnExitCodes, code) {
+			return fmt.Errorf("exit code %d is in both restart_on_exit_codes and buzzkill_on_exit_codes", code)
+		}
+	}
+	return nil
+}
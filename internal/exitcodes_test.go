@@ -0,0 +1,44 @@
+package pp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that exit codes are mapped to the right action
+func TestExitCodeCommand(t *testing.T) {
+	t.Parallel()
+
+	process := Process{
+		SuccessExitCodes:    []int{2},
+		RestartOnExitCodes:  []int{3, 4},
+		BuzzkillOnExitCodes: []int{130},
+	}
+
+	tests := []struct {
+		code    int
+		success bool
+		command ExitCommand
+		handled bool
+	}{
+		{0, true, "", false},
+		{1, false, "", false},
+		{2, true, "", false},
+		{3, false, ExitCommandRestart, true},
+		{4, false, ExitCommandRestart, true},
+		{130, false, ExitCommandBuzzkill, true},
+		{-1, false, "", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.success, process.IsSuccessExitCode(tt.code), "Exit code %d", tt.code)
+		command, handled := process.GetExitCodeCommand(tt.code)
+		assert.Equal(t, tt.command, command, "Exit code %d", tt.code)
+		assert.Equal(t, tt.handled, handled, "Exit code %d", tt.code)
+	}
+
+	assert.NoError(t, process.ValidateExitCodes())
+	process.RestartOnExitCodes = append(process.RestartOnExitCodes, 130)
+	assert.EqualError(t, process.ValidateExitCodes(), "exit code 130 is in both restart_on_exit_codes and buzzkill_on_exit_codes")
+}
@@ -0,0 +1,152 @@
+package pp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// Glob pattern using the .gitignore syntax, matched against slash separated paths relative to a directory
+	globPattern struct {
+		segments []string // Segments of the pattern, ** matches any number of directories
+		negate   bool     // Set by a leading !, matching paths are included again
+		dirOnly  bool     // Set by a trailing /, only directories match
+	}
+
+	// Patterns evaluated in order, the last pattern matching a path decides if it matches
+	globList []globPattern
+
+	// Pattern of an ignore file, matched relative to the directory containing the file
+	ignoreRule struct {
+		directory string
+		pattern   globPattern
+	}
+)
+
+// Files read from watched directories when ignore files are honoured
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// Parses a pattern, patterns without a slash match files and directories at any depth
+func parseGlob(pattern string) (globPattern, error) {
+	glob := globPattern{}
+	if strings.HasPrefix(pattern, "!") {
+		glob.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		// Escaped leading ! or #
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		glob.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if pattern == "" {
+		return glob, fmt.Errorf("empty pattern")
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	glob.segments = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for _, segment := range glob.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return glob, fmt.Errorf("%s: %w", pattern, err)
+		}
+	}
+	return glob, nil
+}
+
+// Parses the patterns in order
+func parseGlobs(patterns []string) (globList, error) {
+	globs := globList{}
+	for _, pattern := range patterns {
+		glob, err := parseGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// Returns true if the pattern matches the path or any of its parent directories
+func (g globPattern) matches(relative string, isDir bool) bool {
+	parts := strings.Split(relative, "/")
+	for i := len(parts); i > 0; i-- {
+		// Every parent is a directory
+		if g.dirOnly && i == len(parts) && !isDir {
+			continue
+		}
+		if matchSegments(g.segments, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches the path segment by segment, ** matches zero or more segments
+func matchSegments(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], parts[0])
+	return err == nil && matched && matchSegments(pattern[1:], parts[1:])
+}
+
+// Applies the patterns to the current result, returning the result of the last matching pattern
+func (l globList) apply(result bool, relative string, isDir bool) bool {
+	for _, glob := range l {
+		if glob.matches(relative, isDir) {
+			result = !glob.negate
+		}
+	}
+	return result
+}
+
+// Returns true if the list contains a pattern that is not negated
+func (l globList) hasPositive() bool {
+	for _, glob := range l {
+		if !glob.negate {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the patterns of the ignore files in the directory, skipping blank lines and comments
+func readIgnoreFiles(directory string) []ignoreRule {
+	rules := []ignoreRule{}
+	for _, name := range ignoreFileNames {
+		file, err := os.Open(filepath.Join(directory, name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			// Invalid patterns are ignored like git does
+			if glob, err := parseGlob(line); err == nil {
+				rules = append(rules, ignoreRule{directory: directory, pattern: glob})
+			}
+		}
+		file.Close()
+	}
+	return rules
+}
@@ -0,0 +1,196 @@
+package pp
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that patterns match like .gitignore patterns
+func TestGlobMatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"*.go", "main.go", false, true},
+		{"*.go", "src/api/main.go", false, true},
+		{"*.go", "main.ts", false, false},
+		{"node_modules", "node_modules", true, true},
+		{"node_modules", "web/node_modules/react/index.js", false, true},
+		{"src/**/generated/*.go", "src/generated/api.go", false, true},
+		{"src/**/generated/*.go", "src/api/v1/generated/api.go", false, true},
+		{"src/**/generated/*.go", "lib/generated/api.go", false, false},
+		{"src/**/generated/*.go", "src/api/generated/api.ts", false, false},
+		{"/build", "build/out.js", false, true},
+		{"/build", "web/build/out.js", false, false},
+		{"docs/", "docs", true, true},
+		{"docs/", "docs", false, false},
+		{"docs/", "docs/index.md", false, true},
+		{"src/**", "src/api/main.go", false, true},
+		{"**/*.test.js", "a/b/c.test.js", false, true},
+		{"?.txt", "a.txt", false, true},
+		{"[ab].txt", "c.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			glob, err := parseGlob(tt.pattern)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.matches, glob.matches(tt.path, tt.isDir))
+		})
+	}
+
+	_, err := parseGlob("[")
+	assert.NotNil(t, err)
+	_, err = parseGlob("!")
+	assert.NotNil(t, err)
+}
+
+// Ensure that the last matching pattern decides, negated patterns include paths again
+func TestGlobListNegation(t *testing.T) {
+	t.Parallel()
+
+	globs, err := parseGlobs([]string{"*.log", "!important.log", "logs/"})
+	assert.Nil(t, err)
+	assert.True(t, globs.apply(false, "debug.log", false))
+	assert.False(t, globs.apply(false, "important.log", false))
+	assert.True(t, globs.apply(false, "logs/important.log", false))
+	assert.False(t, globs.apply(false, "main.go", false))
+	assert.True(t, globs.hasPositive())
+
+	negated, err := parseGlobs([]string{"!*.md"})
+	assert.Nil(t, err)
+	assert.False(t, negated.hasPositive())
+}
+
+// Creates the files, creating their directories
+func createFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// Ensure that the filter matches patterns relative to the watched directory and honours ignore files
+func TestFsFilter(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFiles(t, root, map[string]string{
+		".gitignore":           "# Build output\ndist/\n*.tmp\n",
+		"web/.ignore":          "!keep.tmp\ncache\n",
+		"web/keep.tmp":         "",
+		"web/app.tmp":          "",
+		"web/cache/data.js":    "",
+		"dist/app.js":          "",
+		"src/main.go":          "",
+		"src/generated/api.go": "",
+		"README.md":            "",
+	})
+	process := &Process{Trigger: Trigger{FileSystem: FileSystemTrigger{
+		Watch:          []string{root},
+		Ignore:         []string{"src/generated/", "!dist/app.js"},
+		ContainFilters: []string{"*.go", "*.js", "*.tmp"},
+		Gitignore:      true,
+	}}}
+	context := process.CreateContext(&sync.WaitGroup{})
+	filter, err := context.createFsFilter([]string{root})
+	assert.Nil(t, err)
+	filter.addIgnoreFiles(root)
+	filter.addIgnoreFiles(filepath.Join(root, "web"))
+
+	path := func(name string) string {
+		return filepath.Join(root, filepath.FromSlash(name))
+	}
+	assert.True(t, filter.matches(path("src/main.go")))
+	assert.False(t, filter.matches(path("src/generated/api.go")))
+	assert.False(t, filter.matches(path("README.md")))
+	assert.False(t, filter.matches(path("web/app.tmp")))
+	assert.True(t, filter.matches(path("web/keep.tmp")))
+	assert.False(t, filter.matches(path("web/cache/data.js")))
+	assert.True(t, filter.matches(path("dist/app.js")))
+	assert.True(t, filter.ignored(path(".git"), true))
+	assert.True(t, filter.matches(root))
+
+	// Ignore files are not read unless enabled
+	process.Trigger.FileSystem.Gitignore = false
+	filter, err = context.createFsFilter([]string{root})
+	assert.Nil(t, err)
+	filter.addIgnoreFiles(root)
+	assert.True(t, filter.matches(path("web/app.tmp")))
+
+	process.Trigger.FileSystem.Ignore = []string{"[a-"}
+	_, err = context.createFsFilter([]string{root})
+	assert.NotNil(t, err)
+}
+
+// Ensure that editor temporary files and the other files next to watched files do not match
+func TestFsFilterWatchedFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFiles(t, root, map[string]string{
+		"config.toml": "",
+		"other.toml":  "",
+		"src/main.go": "",
+	})
+	process := &Process{Trigger: Trigger{FileSystem: FileSystemTrigger{Ignore: []string{"!*.swp"}}}}
+	context := process.CreateContext(&sync.WaitGroup{})
+	filter, err := context.createFsFilter([]string{root, filepath.Join(root, "src")})
+	assert.Nil(t, err)
+	filter.files = []string{filepath.Join(root, "config.toml")}
+	filter.dirs = []string{filepath.Join(root, "src")}
+
+	assert.True(t, filter.matches(filepath.Join(root, "config.toml")))
+	assert.False(t, filter.matches(filepath.Join(root, "other.toml")))
+	assert.True(t, filter.matches(filepath.Join(root, "src", "main.go")))
+	assert.False(t, filter.matches(filepath.Join(root, "src", "main.go~")))
+	assert.False(t, filter.matches(filepath.Join(root, "src", "4913")))
+	assert.False(t, filter.matches(filepath.Join(root, "src", "main.go___jb_tmp___")))
+	// Ignore patterns can include temporary files again
+	assert.True(t, filter.matches(filepath.Join(root, "src", ".main.go.swp")))
+}
+
+// Ensure that ignored directories are not watched
+func TestWatchSkipsIgnoredDirectories(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	createFiles(t, root, map[string]string{
+		".gitignore":                     "build/\n",
+		"src/main.go":                    "",
+		"node_modules/react/index.js":    "",
+		"build/out/app.js":               "",
+		"web/node_modules/vue/index.js":  "",
+		"web/components/button/index.js": "",
+	})
+	process := &Process{Silent: true, Trigger: Trigger{FileSystem: FileSystemTrigger{
+		Watch:     []string{root},
+		Ignore:    []string{"node_modules"},
+		Gitignore: true,
+	}}}
+	context := process.CreateContext(&sync.WaitGroup{})
+	filter, err := context.createFsFilter([]string{root})
+	assert.Nil(t, err)
+	watcher, err := fsnotify.NewWatcher()
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	assert.Nil(t, context.watch(root, watcher, filter))
+	watched := []string{}
+	for _, path := range watcher.WatchList() {
+		relative, err := filepath.Rel(root, path)
+		assert.Nil(t, err)
+		watched = append(watched, filepath.ToSlash(relative))
+	}
+	assert.ElementsMatch(t, []string{".", "src", "web", "web/components", "web/components/button"}, watched)
+}
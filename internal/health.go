@@ -0,0 +1,288 @@
+package pp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+type (
+	HealthCheck struct {
+		Http               string `toml:"http" json:"http" yaml:"http"`                                                 // URL that must respond to a GET with a 2xx/3xx status
+		Tcp                string `toml:"tcp" json:"tcp" yaml:"tcp"`                                                    // Address (host:port) that must accept a connection
+		Exec               string `toml:"exec" json:"exec" yaml:"exec"`                                                 // Shell command that must exit successfully
+		LogPattern         string `toml:"log_pattern" json:"log_pattern" yaml:"log_pattern"`                            // Regular expression that must match a line of output
+		Interval           int    `toml:"interval_ms" json:"interval_ms" yaml:"interval_ms"`                            // Time between checks (default 1000)
+		Timeout            int    `toml:"timeout_ms" json:"timeout_ms" yaml:"timeout_ms"`                               // Time before a check fails (default 1000)
+		Retries            int    `toml:"retries" json:"retries" yaml:"retries"`                                        // Consecutive failures before the process is unhealthy (default 3)
+		StartPeriod        int    `toml:"start_period_ms" json:"start_period_ms" yaml:"start_period_ms"`                // Time after starting where failures are not counted
+		RestartOnUnhealthy bool   `toml:"restart_on_unhealthy" json:"restart_on_unhealthy" yaml:"restart_on_unhealthy"` // Restart the process once it is unhealthy
+	}
+
+	// Watches the output of a process for a line matching the log pattern
+	logMatcher struct {
+		pattern *regexp.Regexp
+		matched chan struct{}
+		once    sync.Once
+	}
+
+	// Output stream checked by a log matcher, every stream assembles its own lines
+	logStreamMatcher struct {
+		matcher   *logMatcher
+		assembler lineAssembler
+	}
+)
+
+// Longest partial line kept by a log matcher, longer partial lines such as progress bars are checked and dropped
+const maxLogMatcherLine = 64 * 1024
+
+const (
+	DefaultHealthInterval = 1000 // Milliseconds
+	DefaultHealthTimeout  = 1000 // Milliseconds
+	DefaultHealthRetries  = 3
+)
+
+// Returns true if any health check is configured
+func (h *HealthCheck) Enabled() bool {
+	return h.Http != "" || h.Tcp != "" || h.Exec != "" || h.LogPattern != ""
+}
+
+// Validates the health check configuration
+func (h *HealthCheck) Validate() error {
+	if h.LogPattern != "" {
+		if _, err := regexp.Compile(h.LogPattern); err != nil {
+			return fmt.Errorf("invalid log_pattern: %w", err)
+		}
+	}
+	if h.Tcp != "" {
+		if _, _, err := net.SplitHostPort(h.Tcp); err != nil {
+			return fmt.Errorf("invalid tcp address: %w", err)
+		}
+	}
+	if h.Interval < 0 || h.Timeout < 0 || h.Retries < 0 || h.StartPeriod < 0 {
+		return errors.New("health check durations and retries cannot be negative")
+	}
+	return nil
+}
+
+// Returns the time between checks
+func (h *HealthCheck) GetInterval() time.Duration {
+	if h.Interval <= 0 {
+		return time.Duration(DefaultHealthInterval) * time.Millisecond
+	}
+	return time.Duration(h.Interval) * time.Millisecond
+}
+
+// Returns the time before a check fails
+func (h *HealthCheck) GetTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return time.Duration(DefaultHealthTimeout) * time.Millisecond
+	}
+	return time.Duration(h.Timeout) * time.Millisecond
+}
+
+// Returns the consecutive failures before the process is unhealthy
+func (h *HealthCheck) GetRetries() int {
+	if h.Retries <= 0 {
+		return DefaultHealthRetries
+	}
+	return h.Retries
+}
+
+// Creates a log matcher for the health check, nil if no log pattern is configured
+func (h *HealthCheck) createLogMatcher() *logMatcher {
+	if h.LogPattern == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(h.LogPattern)
+	if err != nil {
+		return nil
+	}
+	return &logMatcher{pattern: pattern, matched: make(chan struct{})}
+}
+
+// Creates a matcher for an output stream of the process
+func (m *logMatcher) stream() *logStreamMatcher {
+	return &logStreamMatcher{matcher: m}
+}
+
+// Checks the line against the pattern
+func (m *logMatcher) match(line []byte) {
+	if m.pattern.Match(bytes.TrimRight(line, "\r")) {
+		m.once.Do(func() { close(m.matched) })
+	}
+}
+
+// Checks every complete line written against the pattern. Partial lines such as prompts are checked
+// once they were kept for LineFlushTimeout. Always consumes the full input
+func (s *logStreamMatcher) Write(p []byte) (int, error) {
+	s.assembler.mutex.Lock()
+	defer s.assembler.mutex.Unlock()
+
+	lines := s.assembler.add(p, s.flushTimedOut)
+	for len(lines) > 0 {
+		index := bytes.IndexByte(lines, '\n')
+		s.matcher.match(lines[:index])
+		lines = lines[index+1:]
+	}
+	if len(s.assembler.partial) > maxLogMatcherLine {
+		s.matcher.match(s.assembler.take())
+	}
+	return len(p), nil
+}
+
+// Checks the partial line once it was kept for LineFlushTimeout, unless it changed since the timer started
+func (s *logStreamMatcher) flushTimedOut(generation int) {
+	s.assembler.mutex.Lock()
+	defer s.assembler.mutex.Unlock()
+	if s.assembler.generation == generation {
+		s.matcher.match(s.assembler.take())
+	}
+}
+
+// Returns true once a line has matched
+func (m *logMatcher) hasMatched() bool {
+	select {
+	case <-m.matched:
+		return true
+	default:
+		return false
+	}
+}
+
+// Runs every configured probe once, returning the first failure
+func (c *ExecutionContext) probeHealth(matcher *logMatcher) error {
+	check := c.Process.HealthCheck
+	timeout := check.GetTimeout()
+
+	if matcher != nil && !matcher.hasMatched() {
+		return errors.New("log pattern not matched yet")
+	}
+
+	if check.Http != "" {
+		client := http.Client{Timeout: timeout}
+		response, err := client.Get(check.Http)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode >= 400 {
+			return fmt.Errorf("http check returned status %d", response.StatusCode)
+		}
+	}
+
+	if check.Tcp != "" {
+		connection, err := net.DialTimeout("tcp", check.Tcp, timeout)
+		if err != nil {
+			return err
+		}
+		connection.Close()
+	}
+
+	if check.Exec != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		shell, flag := defaultShell()
+		cmd := exec.CommandContext(ctx, shell, flag, check.Exec)
+		cmd.Dir = c.Process.Cwd
+		env, err := c.Process.Environment()
+		if err != nil {
+			return err
+		}
+		cmd.Env = env
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("exec check failed: %w %s", err, bytes.TrimSpace(output))
+		}
+	}
+
+	return nil
+}
+
+// Periodically checks the health of the running process until stopped, updating the process status.
+// Returns a function that stops the checks and waits for them to finish
+func (c *ExecutionContext) startHealthCheck(matcher *logMatcher) func() {
+	check := c.Process.HealthCheck
+	if !check.Enabled() {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		startTime := time.Now()
+		startPeriod := time.Duration(check.StartPeriod) * time.Millisecond
+		ticker := time.NewTicker(check.GetInterval())
+		defer ticker.Stop()
+
+		// Log only checks become healthy as soon as the line is written
+		var matched chan struct{}
+		if matcher != nil {
+			matched = matcher.matched
+		}
+
+		failures := 0
+		healthy := false
+		for {
+			select {
+			case <-stop:
+				return
+			case <-matched:
+				matched = nil
+			case <-ticker.C:
+			}
+
+			err := c.probeHealth(matcher)
+			// Another check could have been stopped while probing
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			if err == nil {
+				failures = 0
+				if !healthy || c.GetStatus() == ProcessStatusUnhealthy {
+					healthy = true
+					c.infoWriter.Printf("Health check passed")
+					c.setProcessStatus(ProcessStatusHealthy)
+				}
+				continue
+			}
+
+			if time.Since(startTime) < startPeriod && !healthy {
+				continue
+			}
+			failures++
+			if failures < check.GetRetries() || c.GetStatus() == ProcessStatusUnhealthy {
+				continue
+			}
+
+			c.errorWriter.Printf("Health check failed %d times - %s", failures, err.Error())
+			c.setProcessStatus(ProcessStatusUnhealthy)
+			if check.RestartOnUnhealthy {
+				c.errorWriter.Eventf(OutputLine{Event: EventRestarting}, "Restarting unhealthy process")
+				c.restartRequested.Store(true)
+				go c.killExecution()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
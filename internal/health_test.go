@@ -0,0 +1,105 @@
+package pp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that the log matcher only matches whole lines, even when they are split across writes
+func TestLogMatcher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		pattern string
+		writes  []string
+		matched bool
+	}{
+		{"single line", "^ready$", []string{"ready\n"}, true},
+		{"split line", "^listening on :8080$", []string{"listen", "ing on ", ":8080\n"}, true},
+		{"windows line endings", "^ready$", []string{"starting\r\nready\r\n"}, true},
+		{"partial line", "ready>", []string{"ready> "}, true},
+		{"no match", "^ready$", []string{"not ready\n", "still not ready\n"}, false},
+		{"anchors per line", "^ready$", []string{"re", "\nady\n"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := HealthCheck{LogPattern: tt.pattern}
+			matcher := check.createLogMatcher()
+			stream := matcher.stream()
+			for _, write := range tt.writes {
+				n, err := stream.Write([]byte(write))
+				assert.NoError(t, err)
+				assert.Equal(t, len(write), n, "Should consume the full write")
+			}
+			// Partial lines are checked after the flush timeout
+			if tt.matched {
+				assert.Eventually(t, matcher.hasMatched, time.Second, 10*time.Millisecond)
+			} else {
+				assert.False(t, matcher.hasMatched())
+			}
+		})
+	}
+}
+
+// Ensure that streams are assembled separately, partial lines wait for the flush timeout and are capped
+func TestLogMatcherStreams(t *testing.T) {
+	t.Parallel()
+
+	check := HealthCheck{LogPattern: "^ready$"}
+	matcher := check.createLogMatcher()
+	stdout, stderr := matcher.stream(), matcher.stream()
+	stdout.Write([]byte("rea"))
+	stderr.Write([]byte("dy\n"))
+	stdout.Write([]byte("d\n"))
+	assert.False(t, matcher.hasMatched(), "Lines of different streams should not be joined")
+
+	// Partial lines are only checked once they were kept for the flush timeout
+	check = HealthCheck{LogPattern: "ready"}
+	matcher = check.createLogMatcher()
+	stdout = matcher.stream()
+	stdout.Write([]byte("ready"))
+	assert.False(t, matcher.hasMatched())
+	stdout.Write([]byte(" to serve\n"))
+	assert.True(t, matcher.hasMatched())
+
+	// Output without newlines does not grow the buffer without limit
+	check = HealthCheck{LogPattern: "^100%"}
+	matcher = check.createLogMatcher()
+	stdout = matcher.stream()
+	progress := []byte(strings.Repeat("100%\r", 1024))
+	for i := 0; i <= maxLogMatcherLine/len(progress); i++ {
+		stdout.Write(progress)
+	}
+	assert.True(t, matcher.hasMatched())
+	stdout.assembler.mutex.Lock()
+	assert.LessOrEqual(t, len(stdout.assembler.partial), maxLogMatcherLine)
+	stdout.assembler.mutex.Unlock()
+}
+
+// Ensure that invalid health checks are rejected
+func TestHealthCheckValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&HealthCheck{}).Validate())
+	assert.NoError(t, (&HealthCheck{Tcp: "localhost:80", LogPattern: "ready"}).Validate())
+	assert.Error(t, (&HealthCheck{LogPattern: "("}).Validate())
+	assert.Error(t, (&HealthCheck{Tcp: "localhost"}).Validate())
+	assert.Error(t, (&HealthCheck{Retries: -1}).Validate())
+}
+
+// Ensure that the health statuses do not change the values of the existing statuses
+func TestProcessStatusValues(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []ProcessStatus{0, 1, 2, 3, 4, 5}, []ProcessStatus{
+		ProcessStatusNotStarted, ProcessStatusRunning, ProcessStatusRestarting,
+		ProcessStatusWaitingTrigger, ProcessStatusExited, ProcessStatusFailed,
+	})
+	assert.Greater(t, ProcessStatusHealthy, ProcessStatusFailed)
+	assert.Greater(t, ProcessStatusUnhealthy, ProcessStatusFailed)
+}
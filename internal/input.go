@@ -0,0 +1,51 @@
+package pp
+
+import "strings"
+
+const (
+	InputSeparator = ":"    // Separates the process from the input written to it
+	InputEscape    = `\`    // Starts a command while a process is focused, doubled to write a literal backslash
+	FocusHotkey    = "\x1d" // Ctrl+] leaves focus mode
+)
+
+// A line of input, either a command for process party or text written to a process
+type Input struct {
+	Command string   // Command for process party (status, restart, focus etc.)
+	Args    []string // Arguments of the command
+	Target  string   // Processes the text is written to, empty when written to the focused process
+	Text    string   // Text written to the processes, kept exactly as entered
+	Unfocus bool     // Set when the focus hotkey was entered
+}
+
+// Parses a line read from the standard input. While a process is focused every line is written to it as entered,
+// unless it is the focus hotkey or starts with the escape character. Otherwise lines starting with a command are
+// commands, and any other line is written to the processes before the first separator, so the text keeps any colons
+func ParseInput(line string, focused bool, isCommand func(string) bool) Input {
+	line = strings.TrimRight(line, "\r\n")
+
+	if focused {
+		switch {
+		case strings.TrimSpace(line) == FocusHotkey:
+			return Input{Unfocus: true}
+		case strings.HasPrefix(line, InputEscape+InputEscape):
+			return Input{Text: line[len(InputEscape):]}
+		case strings.HasPrefix(line, InputEscape):
+			return ParseInput(line[len(InputEscape):], false, isCommand)
+		}
+		return Input{Text: line}
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Input{}
+	}
+	if isCommand(fields[0]) {
+		return Input{Command: fields[0], Args: fields[1:]}
+	}
+	target, text, found := strings.Cut(line, InputSeparator)
+	if !found {
+		// Unknown commands are reported by the caller
+		return Input{Command: fields[0], Args: fields[1:]}
+	}
+	return Input{Target: strings.TrimSpace(target), Text: text}
+}
@@ -0,0 +1,60 @@
+package pp
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that input is split on the first separator only, and that focus mode writes lines as entered
+func TestParseInput(t *testing.T) {
+	t.Parallel()
+
+	isCommand := func(command string) bool {
+		return command == "status" || command == "restart" || command == "focus"
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		focused bool
+		input   Input
+	}{
+		{"empty", "\n", false, Input{}},
+		{"whitespace", "  \t\n", false, Input{}},
+		{"command", "status\n", false, Input{Command: "status", Args: []string{}}},
+		{"command with args", "restart api*\r\n", false, Input{Command: "restart", Args: []string{"api*"}}},
+		{"unknown command", "reload api\n", false, Input{Command: "reload", Args: []string{"api"}}},
+		{"write", "api:hello\n", false, Input{Target: "api", Text: "hello"}},
+		{"write keeps colons", "api:GET http://x:8080/\n", false, Input{Target: "api", Text: "GET http://x:8080/"}},
+		{"write keeps whitespace", " api:  indented \n", false, Input{Target: "api", Text: "  indented "}},
+		{"write to name with spaces", "my process:hello\n", false, Input{Target: "my process", Text: "hello"}},
+		{"write empty", "api:\n", false, Input{Target: "api", Text: ""}},
+		{"command with separator", "restart api:x\n", false, Input{Command: "restart", Args: []string{"api:x"}}},
+		{"focused write", "status\n", true, Input{Text: "status"}},
+		{"focused keeps everything", "  api:GET http://x \n", true, Input{Text: "  api:GET http://x "}},
+		{"focused empty line", "\n", true, Input{Text: ""}},
+		{"focused escape command", `\status` + "\n", true, Input{Command: "status", Args: []string{}}},
+		{"focused escape write", `\web:hello` + "\n", true, Input{Target: "web", Text: "hello"}},
+		{"focused literal escape", `\\n` + "\n", true, Input{Text: `\n`}},
+		{"focused hotkey", FocusHotkey + "\n", true, Input{Unfocus: true}},
+		{"hotkey when not focused", FocusHotkey + "\n", false, Input{Command: FocusHotkey, Args: []string{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.input, ParseInput(tt.line, tt.focused, isCommand))
+		})
+	}
+}
+
+// Ensure that the null device is not mistaken for a terminal, reading it returns EOF forever
+func TestIsTerminalNullDevice(t *testing.T) {
+	t.Parallel()
+
+	devNull, err := os.Open(os.DevNull)
+	assert.NoError(t, err)
+	defer devNull.Close()
+	assert.False(t, IsTerminal(devNull))
+}
@@ -0,0 +1,235 @@
+package pp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	color "github.com/fatih/color"
+)
+
+type (
+	LogFile struct {
+		Path        string `toml:"path" json:"path" yaml:"path"`                         // File the output is written to, {name} is replaced by the process name
+		Prefix      bool   `toml:"prefix" json:"prefix" yaml:"prefix"`                   // Start every line with the process prefix
+		Colour      bool   `toml:"color" json:"color" yaml:"color"`                      // Keep ANSI colours in the output
+		MaxSize     int    `toml:"max_size_mb" json:"max_size_mb" yaml:"max_size_mb"`    // Rotate the file once it is larger (megabytes, 0 to never rotate on size)
+		RotateHours int    `toml:"rotate_hours" json:"rotate_hours" yaml:"rotate_hours"` // Rotate the file once it is older (hours, 0 to never rotate on age)
+		MaxFiles    int    `toml:"max_files" json:"max_files" yaml:"max_files"`          // Rotated files kept (0 to keep all)
+		MaxAgeDays  int    `toml:"max_age_days" json:"max_age_days" yaml:"max_age_days"` // Days rotated files are kept (0 to keep all)
+	}
+
+	// Writes the output of a single process to its log file, processes sharing a file can format their lines differently
+	logFileWriter struct {
+		file    *rotatingFile
+		options LogFile
+	}
+
+	// Log file shared by every process writing to the same path, rotated by size and age
+	rotatingFile struct {
+		mutex   sync.Mutex
+		options LogFile
+		file    *os.File
+		size    int64
+		opened  time.Time
+		users   int // Processes writing to the file, the file is closed once all are done
+	}
+)
+
+const logFileTimeLayout = "20060102-150405.000"
+
+// Log files that are open, by absolute path
+var (
+	openLogFiles      = map[string]*rotatingFile{}
+	openLogFilesMutex sync.Mutex
+)
+
+// Validates the log file configuration
+func (l *LogFile) Validate() error {
+	if l.MaxSize < 0 || l.RotateHours < 0 || l.MaxFiles < 0 || l.MaxAgeDays < 0 {
+		return errors.New("log file limits cannot be negative")
+	}
+	return nil
+}
+
+// Returns the log file of the process, the global log file is used if the process has none
+func (p *Process) GetLogFile() LogFile {
+	logFile := p.LogFile
+	if logFile.Path == "" {
+		logFile = p.GlobalLogFile
+	}
+	logFile.Path = strings.ReplaceAll(logFile.Path, "{name}", p.displayName())
+	return logFile
+}
+
+// Opens the log file for another process, creating its directory if needed
+func openLogFile(options LogFile) (*rotatingFile, error) {
+	path, err := filepath.Abs(options.Path)
+	if err != nil {
+		return nil, err
+	}
+	options.Path = path
+
+	openLogFilesMutex.Lock()
+	defer openLogFilesMutex.Unlock()
+	if file, ok := openLogFiles[path]; ok {
+		file.mutex.Lock()
+		file.users++
+		file.mutex.Unlock()
+		return file, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file := &rotatingFile{options: options, users: 1}
+	// Files left by an earlier party are rotated once they are too old
+	if stat, err := os.Stat(path); err == nil && file.expired(stat.ModTime()) {
+		if err := file.rotate(); err != nil {
+			return nil, err
+		}
+	} else if err := file.open(); err != nil {
+		return nil, err
+	}
+	openLogFiles[path] = file
+	return file, nil
+}
+
+// Returns true if a file opened at the time has to be rotated
+func (f *rotatingFile) expired(opened time.Time) bool {
+	return f.options.RotateHours > 0 && time.Since(opened) >= time.Duration(f.options.RotateHours)*time.Hour
+}
+
+// Opens the file for appending
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = stat.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+// Moves the current file to a file named after the time it was rotated, starts a new file and removes old files
+func (f *rotatingFile) rotate() error {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	extension := filepath.Ext(f.options.Path)
+	rotated := strings.TrimSuffix(f.options.Path, extension) + "-" + time.Now().Format(logFileTimeLayout) + extension
+	if err := os.Rename(f.options.Path, rotated); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f.removeRotated()
+	return f.open()
+}
+
+// Removes the rotated files exceeding the number of files or the age kept
+func (f *rotatingFile) removeRotated() {
+	rotated := f.rotatedFiles()
+	for i, path := range rotated {
+		tooMany := f.options.MaxFiles > 0 && i < len(rotated)-f.options.MaxFiles
+		tooOld := false
+		if stat, err := os.Stat(path); err == nil && f.options.MaxAgeDays > 0 {
+			tooOld = time.Since(stat.ModTime()) > time.Duration(f.options.MaxAgeDays)*24*time.Hour
+		}
+		if tooMany || tooOld {
+			os.Remove(path)
+		}
+	}
+}
+
+// Returns the rotated files, oldest first
+func (f *rotatingFile) rotatedFiles() []string {
+	extension := filepath.Ext(f.options.Path)
+	base := strings.TrimSuffix(filepath.Base(f.options.Path), extension) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.options.Path))
+	if err != nil {
+		return nil
+	}
+	rotated := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base) || !strings.HasSuffix(name, extension) {
+			continue
+		}
+		if _, err := time.Parse(logFileTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, base), extension)); err != nil {
+			continue
+		}
+		rotated = append(rotated, filepath.Join(filepath.Dir(f.options.Path), name))
+	}
+	// The time layout sorts in the order the files were rotated
+	sort.Strings(rotated)
+	return rotated
+}
+
+// Writes the output to the file, rotating it first if it is too large or too old
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	tooLarge := f.options.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > int64(f.options.MaxSize)*1024*1024
+	if tooLarge || f.expired(f.opened) {
+		if err := f.rotate(); err != nil {
+			return 0, fmt.Errorf("could not rotate %s: %w", f.options.Path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Stops writing to the file for a process, closing it once no process writes to it
+func (f *rotatingFile) release() {
+	openLogFilesMutex.Lock()
+	defer openLogFilesMutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.users--
+	if f.users > 0 {
+		return
+	}
+	delete(openLogFiles, f.options.Path)
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// Colours the text if the log file keeps colours, even if the terminal does not support colours
+func (w *logFileWriter) colour(attribute color.Attribute, text string) string {
+	if !w.options.Colour {
+		return text
+	}
+	colour := color.New(attribute)
+	colour.EnableColor()
+	return colour.Sprint(text)
+}
+
+// Removes the colours written by the process if the log file does not keep colours
+func (w *logFileWriter) clean(text string) string {
+	if w.options.Colour {
+		return text
+	}
+	return ansiSequence.ReplaceAllString(text, "")
+}
+
+// Writes the line to the log file, errors are ignored so the output of the process is not affected
+func (w *logFileWriter) writeLine(line string) {
+	w.file.Write([]byte(line))
+}
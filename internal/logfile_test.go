@@ -0,0 +1,174 @@
+package pp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Reads the log file, failing the test if it cannot be read
+func readLogFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(content)
+}
+
+// Ensure that the log file of the process falls back to the global log file and replaces the name
+func TestGetLogFile(t *testing.T) {
+	t.Parallel()
+
+	process := &Process{Name: "server", Prefix: "SRV", GlobalLogFile: LogFile{Path: "logs/{name}.log", MaxFiles: 2}}
+	assert.Equal(t, LogFile{Path: "logs/server.log", MaxFiles: 2}, process.GetLogFile())
+
+	process.Name = ""
+	assert.Equal(t, "logs/SRV.log", process.GetLogFile().Path)
+
+	process.LogFile = LogFile{Path: "server.log", Prefix: true}
+	assert.Equal(t, LogFile{Path: "server.log", Prefix: true}, process.GetLogFile())
+
+	assert.Nil(t, (&LogFile{MaxSize: 1}).Validate())
+	assert.NotNil(t, (&LogFile{MaxFiles: -1}).Validate())
+}
+
+// Ensure that the output is written with or without the prefix and colours
+func TestLogFileFormatting(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		options  LogFile
+		expected string
+	}{
+		{"plain", LogFile{}, "hello\nworld\nmessage\n"},
+		{"prefix", LogFile{Prefix: true}, "[TEST] hello\n[TEST] world\n[TEST] message\n"},
+		{"colour", LogFile{Colour: true}, "\x1b[1mhello\x1b[0m\nworld\n\x1b[90mmessage\x1b[0m\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.Path = filepath.Join(t.TempDir(), "test.log")
+			file, err := openLogFile(tt.options)
+			assert.Nil(t, err)
+			defer file.release()
+
+			process := &Process{Name: "test", Prefix: "TEST", Color: ColourCmdGreen}
+			writer := &customWriter{w: &mockWriter{}, severity: "info", process: process, file: &logFileWriter{file: file, options: tt.options}}
+			n, err := writer.Write([]byte("\x1b[1mhello\x1b[0m\nworld\n"))
+			assert.Nil(t, err)
+			assert.Equal(t, 20, n)
+			writer.Printf("message")
+
+			assert.Equal(t, tt.expected, readLogFile(t, tt.options.Path))
+		})
+	}
+}
+
+// Ensure that silent processes only write to their log file
+func TestLogFileSilent(t *testing.T) {
+	t.Parallel()
+
+	options := LogFile{Path: filepath.Join(t.TempDir(), "test.log")}
+	file, err := openLogFile(options)
+	assert.Nil(t, err)
+	defer file.release()
+
+	mock := &mockWriter{}
+	process := &Process{Name: "test", Silent: true}
+	writer := &customWriter{w: mock, severity: "error", process: process, file: &logFileWriter{file: file, options: options}}
+	n, err := writer.Write([]byte("failed\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, n)
+	assert.Empty(t, mock.written)
+	assert.Equal(t, "failed\n", readLogFile(t, options.Path))
+}
+
+// Ensure that processes writing to the same path share the file until the last one is done
+func TestLogFileShared(t *testing.T) {
+	t.Parallel()
+
+	options := LogFile{Path: filepath.Join(t.TempDir(), "logs", "shared.log")}
+	first, err := openLogFile(options)
+	assert.Nil(t, err)
+	second, err := openLogFile(options)
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+
+	first.Write([]byte("first\n"))
+	first.release()
+	_, err = second.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	second.release()
+	_, err = second.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	assert.Equal(t, "first\nsecond\n", readLogFile(t, options.Path))
+}
+
+// Ensure that the file is rotated once it is too large or too old
+func TestLogFileRotation(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	options := LogFile{Path: filepath.Join(directory, "test.log"), MaxSize: 1, RotateHours: 1}
+	file, err := openLogFile(options)
+	assert.Nil(t, err)
+	defer file.release()
+
+	// Size
+	large := []byte(strings.Repeat("a", 1024*1024-1) + "\n")
+	_, err = file.Write(large)
+	assert.Nil(t, err)
+	assert.Empty(t, file.rotatedFiles())
+	_, err = file.Write([]byte("rotated\n"))
+	assert.Nil(t, err)
+	assert.Len(t, file.rotatedFiles(), 1)
+	assert.Equal(t, "rotated\n", readLogFile(t, options.Path))
+
+	// Age
+	time.Sleep(5 * time.Millisecond)
+	file.opened = time.Now().Add(-time.Hour)
+	_, err = file.Write([]byte("new\n"))
+	assert.Nil(t, err)
+	rotated := file.rotatedFiles()
+	assert.Len(t, rotated, 2)
+	assert.Equal(t, "rotated\n", readLogFile(t, rotated[1]))
+	assert.Equal(t, "new\n", readLogFile(t, options.Path))
+}
+
+// Ensure that rotated files are removed once there are too many or they are too old
+func TestLogFileRetention(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	options := LogFile{Path: filepath.Join(directory, "test.log"), MaxFiles: 2, MaxAgeDays: 1}
+	rotated := []string{}
+	for i, name := range []string{"test-20240101-000000.000.log", "test-20240102-000000.000.log", "test-20240103-000000.000.log", "test-20240104-000000.000.log"} {
+		path := filepath.Join(directory, name)
+		assert.Nil(t, os.WriteFile(path, []byte("old\n"), 0644))
+		rotated = append(rotated, path)
+		// Only the first file is too old
+		if i == 0 {
+			old := time.Now().Add(-48 * time.Hour)
+			assert.Nil(t, os.Chtimes(path, old, old))
+		}
+	}
+	// Files not created by the rotation are kept
+	unrelated := filepath.Join(directory, "test-notes.log")
+	assert.Nil(t, os.WriteFile(unrelated, []byte("notes\n"), 0644))
+
+	file := &rotatingFile{options: options}
+	file.removeRotated()
+	assert.Equal(t, rotated[2:], file.rotatedFiles())
+	assert.FileExists(t, unrelated)
+
+	options.MaxFiles = 0
+	file = &rotatingFile{options: options}
+	old := time.Now().Add(-48 * time.Hour)
+	assert.Nil(t, os.Chtimes(rotated[2], old, old))
+	file.removeRotated()
+	assert.Equal(t, rotated[3:], file.rotatedFiles())
+}
@@ -0,0 +1,137 @@
+package pp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of triggers counted by the metrics
+const (
+	TriggerKindFs      = "fs"
+	TriggerKindProcess = "process"
+	TriggerKindManual  = "manual"
+)
+
+// Counters of a process exported by the metrics endpoint
+type processMetrics struct {
+	mutex        sync.Mutex
+	exitCodes    map[int]int              // Executions that ended with every exit code
+	triggers     map[string]*atomic.Int64 // Trigger fires per trigger kind
+	runningSince atomic.Int64             // Unix nanoseconds the process started running, 0 if it is not running
+	stdoutLines  atomic.Int64
+	stderrLines  atomic.Int64
+	buzzkills    atomic.Int64 // Buzzkills emitted by the process
+}
+
+func newProcessMetrics() *processMetrics {
+	return &processMetrics{
+		exitCodes: map[int]int{},
+		triggers: map[string]*atomic.Int64{
+			TriggerKindFs:      {},
+			TriggerKindProcess: {},
+			TriggerKindManual:  {},
+		},
+	}
+}
+
+// Updates the uptime with the new status of the process
+func (m *processMetrics) statusChanged(status ProcessStatus) {
+	if !status.IsRunning() {
+		m.runningSince.Store(0)
+		return
+	}
+	// Becoming healthy or unhealthy does not restart the uptime
+	m.runningSince.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// Counts an execution that ended with the exit code
+func (m *processMetrics) exited(code int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.exitCodes[code]++
+}
+
+// Returns the seconds the process has been running for, 0 if it is not running
+func (m *processMetrics) uptime() float64 {
+	since := m.runningSince.Load()
+	if since == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, since)).Seconds()
+}
+
+// Escapes a label value of the Prometheus text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Writes the metrics of every process in the Prometheus text format
+func WriteMetrics(w io.Writer, contexts []*ExecutionContext) {
+	family := func(name string, kind string, help string, samples func(process string, context *ExecutionContext)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, context := range contexts {
+			samples(`process="`+escapeLabel(context.displayName())+`"`, context)
+		}
+	}
+
+	family("process_party_process_status", "gauge", "Current status of the process, 1 for the current status.",
+		func(process string, context *ExecutionContext) {
+			current := context.GetStatus()
+			for status := ProcessStatusNotStarted; status <= ProcessStatusStopped; status++ {
+				value := 0
+				if status == current {
+					value = 1
+				}
+				fmt.Fprintf(w, "process_party_process_status{%s,status=\"%s\"} %d\n", process, status.metricName(), value)
+			}
+		})
+
+	family("process_party_process_uptime_seconds", "gauge", "Seconds the process has been running for, 0 if it is not running.",
+		func(process string, context *ExecutionContext) {
+			fmt.Fprintf(w, "process_party_process_uptime_seconds{%s} %.3f\n", process, context.metrics.uptime())
+		})
+
+	family("process_party_process_restarts_total", "counter", "Restarts of the process, including requested restarts.",
+		func(process string, context *ExecutionContext) {
+			fmt.Fprintf(w, "process_party_process_restarts_total{%s} %d\n", process, context.restarts.Load())
+		})
+
+	family("process_party_process_exits_total", "counter", "Executions of the process that ended, by exit code (-1 if it was killed or did not start).",
+		func(process string, context *ExecutionContext) {
+			context.metrics.mutex.Lock()
+			codes := []int{}
+			counts := map[int]int{}
+			for code, count := range context.metrics.exitCodes {
+				codes = append(codes, code)
+				counts[code] = count
+			}
+			context.metrics.mutex.Unlock()
+			sort.Ints(codes)
+			for _, code := range codes {
+				fmt.Fprintf(w, "process_party_process_exits_total{%s,code=\"%d\"} %d\n", process, code, counts[code])
+			}
+		})
+
+	family("process_party_process_triggers_total", "counter", "Triggers fired for the process, by trigger kind.",
+		func(process string, context *ExecutionContext) {
+			for _, kind := range []string{TriggerKindFs, TriggerKindProcess, TriggerKindManual} {
+				fmt.Fprintf(w, "process_party_process_triggers_total{%s,kind=\"%s\"} %d\n", process, kind, context.metrics.triggers[kind].Load())
+			}
+		})
+
+	family("process_party_process_output_lines_total", "counter", "Lines of output written by the process, by stream.",
+		func(process string, context *ExecutionContext) {
+			fmt.Fprintf(w, "process_party_process_output_lines_total{%s,stream=\"stdout\"} %d\n", process, context.metrics.stdoutLines.Load())
+			fmt.Fprintf(w, "process_party_process_output_lines_total{%s,stream=\"stderr\"} %d\n", process, context.metrics.stderrLines.Load())
+		})
+
+	family("process_party_process_buzzkills_total", "counter", "Buzzkills emitted by the process, stopping the other processes.",
+		func(process string, context *ExecutionContext) {
+			fmt.Fprintf(w, "process_party_process_buzzkills_total{%s} %d\n", process, context.metrics.buzzkills.Load())
+		})
+}
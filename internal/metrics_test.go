@@ -0,0 +1,109 @@
+package pp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that the uptime starts when the process runs and is kept while its health changes
+func TestMetricsUptime(t *testing.T) {
+	t.Parallel()
+
+	metrics := newProcessMetrics()
+	assert.Equal(t, 0.0, metrics.uptime())
+
+	metrics.statusChanged(ProcessStatusRunning)
+	since := metrics.runningSince.Load()
+	assert.NotZero(t, since)
+	time.Sleep(10 * time.Millisecond)
+	metrics.statusChanged(ProcessStatusHealthy)
+	assert.Equal(t, since, metrics.runningSince.Load(), "Health changes should not restart the uptime")
+	assert.Greater(t, metrics.uptime(), 0.0)
+
+	metrics.statusChanged(ProcessStatusRestarting)
+	assert.Equal(t, 0.0, metrics.uptime())
+}
+
+// Ensure that the metrics are written in the Prometheus text format
+func TestWriteMetrics(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	api := Process{Name: "api"}
+	inline := Process{Prefix: `go "run"`}
+	contexts := []*ExecutionContext{api.CreateContext(&wg), inline.CreateContext(&wg)}
+	for _, context := range contexts {
+		context.SetOutput(&strings.Builder{}, &strings.Builder{})
+	}
+
+	context := contexts[0]
+	context.Status = ProcessStatusCrashLooping
+	context.restarts.Add(3)
+	context.metrics.exited(1)
+	context.metrics.exited(1)
+	context.metrics.exited(-1)
+	context.metrics.triggers[TriggerKindFs].Add(2)
+	context.metrics.buzzkills.Add(1)
+	context.infoWriter.Write([]byte("listening\nready\n"))
+	context.errorWriter.Write([]byte("warning\n"))
+	context.infoWriter.Printf("Messages from process party are not counted")
+
+	builder := &strings.Builder{}
+	WriteMetrics(builder, contexts)
+	metrics := builder.String()
+
+	for _, line := range []string{
+		"# TYPE process_party_process_status gauge",
+		`process_party_process_status{process="api",status="crash_looping"} 1`,
+		`process_party_process_status{process="api",status="running"} 0`,
+		`process_party_process_status{process="go \"run\"",status="not_started"} 1`,
+		`process_party_process_uptime_seconds{process="api"} 0.000`,
+		"# TYPE process_party_process_restarts_total counter",
+		`process_party_process_restarts_total{process="api"} 3`,
+		`process_party_process_exits_total{process="api",code="-1"} 1`,
+		`process_party_process_exits_total{process="api",code="1"} 2`,
+		`process_party_process_triggers_total{process="api",kind="fs"} 2`,
+		`process_party_process_triggers_total{process="api",kind="process"} 0`,
+		`process_party_process_output_lines_total{process="api",stream="stdout"} 2`,
+		`process_party_process_output_lines_total{process="api",stream="stderr"} 1`,
+		`process_party_process_buzzkills_total{process="api"} 1`,
+	} {
+		assert.Contains(t, metrics, line+"\n")
+	}
+	assert.Less(t, strings.Index(metrics, `code="-1"`), strings.Index(metrics, `code="1"`), "Exit codes should be sorted")
+	assert.NotContains(t, metrics, `process_party_process_exits_total{process="go \"run\""`)
+
+	recorder := httptest.NewRecorder()
+	NewApiHandler(contexts).ServeHTTP(recorder, httptest.NewRequest("GET", "http://localhost/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, recorder.Body.String(), `process_party_process_restarts_total{process="api"} 3`)
+}
+
+// Ensure that metrics can be written while the status of a process changes
+func TestWriteMetricsStatusChanges(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	process := Process{Name: "api"}
+	context := process.CreateContext(&wg)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			context.setProcessStatus(ProcessStatusRunning)
+			context.setProcessStatus(ProcessStatusRestarting)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		WriteMetrics(&strings.Builder{}, []*ExecutionContext{context})
+	}
+	<-done
+	assert.Equal(t, ProcessStatusRestarting, context.GetStatus())
+}
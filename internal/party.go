@@ -0,0 +1,56 @@
+package pp
+
+import "sync"
+
+// Contexts that run together. Processes that ended stay idle so they can be started again,
+// until every process of the party has ended
+type party struct {
+	mutex    sync.Mutex
+	contexts []*ExecutionContext
+	idle     map[*ExecutionContext]bool
+	ended    chan struct{} // Closed once every process of the party is idle
+}
+
+// Makes the contexts a party that ends once all of their processes have ended
+func joinParty(contexts ...*ExecutionContext) {
+	p := &party{
+		contexts: contexts,
+		idle:     map[*ExecutionContext]bool{},
+		ended:    make(chan struct{}),
+	}
+	for _, context := range contexts {
+		context.party = p
+	}
+}
+
+// Marks the process of the context as ended, ends the party if every process has ended
+func (p *party) setIdle(context *ExecutionContext) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.idle[context] = true
+	for _, c := range p.contexts {
+		if !p.idle[c] {
+			return
+		}
+	}
+	select {
+	case <-p.ended:
+	default:
+		close(p.ended)
+	}
+}
+
+// Marks the process of the context as running again, returns false if the party has already ended
+func (p *party) wake(context *ExecutionContext) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	select {
+	case <-p.ended:
+		return false
+	default:
+	}
+	p.idle[context] = false
+	return true
+}
@@ -0,0 +1,59 @@
+package pp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure commands are split following POSIX quoting rules
+func TestSplitCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		errors   bool
+	}{
+		{"single word", "ls", []string{"ls"}, false},
+		{"multiple words", "go run main.go", []string{"go", "run", "main.go"}, false},
+		{"repeated whitespace", "  go   run\tmain.go ", []string{"go", "run", "main.go"}, false},
+		{"double quotes", `echo "hello world"`, []string{"echo", "hello world"}, false},
+		{"single quotes", `echo 'hello "world"'`, []string{"echo", `hello "world"`}, false},
+		{"escaped space", `echo hello\ world`, []string{"echo", "hello world"}, false},
+		{"escapes in double quotes", `echo "a \"b\" \$c \d"`, []string{"echo", `a "b" $c \d`}, false},
+		{"no escapes in single quotes", `echo 'a\b'`, []string{"echo", `a\b`}, false},
+		{"adjacent quotes join", `echo a"b"'c'`, []string{"echo", "abc"}, false},
+		{"empty quotes", `echo ""`, []string{"echo", ""}, false},
+		{"colons and operators kept", "curl http://x && ls", []string{"curl", "http://x", "&&", "ls"}, false},
+		{"empty", "", []string{}, false},
+		{"unterminated double quote", `echo "hello`, nil, true},
+		{"unterminated single quote", `echo 'hello`, nil, true},
+		{"trailing backslash", `echo \`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := splitCommand(tt.input)
+			if tt.errors {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, words)
+		})
+	}
+}
+
+// Ensure that joined arguments split back into the same arguments
+func TestJoinCommand(t *testing.T) {
+	t.Parallel()
+
+	args := []string{"plain", "with space", "it's", `"quoted"`, "", "$HOME", "a&&b"}
+	joined := joinCommand(args)
+	words, err := splitCommand(joined)
+	assert.NoError(t, err)
+	assert.Equal(t, args, words)
+	assert.Equal(t, "plain", quoteArg("plain"))
+}
@@ -0,0 +1,62 @@
+package pp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Signals that can be used on every platform, platform specific signals are in platformSignals
+var commonSignals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+const (
+	DefaultStopSignal  = "SIGINT"
+	DefaultStopTimeout = 10 // Seconds
+)
+
+// Parses a signal name (SIGTERM, TERM, term) or number (15) into a signal
+func ParseSignal(name string) (os.Signal, error) {
+	if number, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+		if number < 1 || number > maxSignal {
+			return nil, fmt.Errorf("signal number %d is out of range 1-%d", number, maxSignal)
+		}
+		return syscall.Signal(number), nil
+	}
+
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if signal, exists := commonSignals[name]; exists {
+		return signal, nil
+	}
+	if signal, exists := platformSignals[name]; exists {
+		return signal, nil
+	}
+	return nil, fmt.Errorf("unknown signal %q", name)
+}
+
+// Returns the signal used to gracefully stop the process
+func (p *Process) GetStopSignal() (os.Signal, error) {
+	if p.StopSignal == "" {
+		return ParseSignal(DefaultStopSignal)
+	}
+	return ParseSignal(p.StopSignal)
+}
+
+// Returns how long to wait for the process to stop before killing it
+func (p *Process) GetStopTimeout() time.Duration {
+	if p.StopTimeout <= 0 {
+		return time.Duration(DefaultStopTimeout) * time.Second
+	}
+	return time.Duration(p.StopTimeout) * time.Second
+}
@@ -0,0 +1,60 @@
+package pp
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure signal names and numbers are parsed
+func TestParseSignal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected syscall.Signal
+		errors   bool
+	}{
+		{"full name", "SIGTERM", syscall.SIGTERM, false},
+		{"short name", "TERM", syscall.SIGTERM, false},
+		{"lower case", "sigquit", syscall.SIGQUIT, false},
+		{"number", "9", syscall.SIGKILL, false},
+		{"unknown", "SIGNOPE", 0, true},
+		{"zero", "0", 0, true},
+		{"negative", "-5", 0, true},
+		{"too large", "1000", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal, err := ParseSignal(tt.input)
+			if tt.errors {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, signal)
+		})
+	}
+}
+
+// Ensure the stop defaults are applied
+func TestStopDefaults(t *testing.T) {
+	t.Parallel()
+
+	process := Process{}
+	signal, err := process.GetStopSignal()
+	assert.NoError(t, err)
+	assert.Equal(t, syscall.SIGINT, signal)
+	assert.Equal(t, time.Duration(DefaultStopTimeout)*time.Second, process.GetStopTimeout())
+
+	process.StopSignal = "SIGQUIT"
+	process.StopTimeout = 3
+	signal, err = process.GetStopSignal()
+	assert.NoError(t, err)
+	assert.Equal(t, syscall.SIGQUIT, signal)
+	assert.Equal(t, 3*time.Second, process.GetStopTimeout())
+}
@@ -0,0 +1,132 @@
+package pp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SuccessPolicy string
+
+const (
+	SuccessAll           SuccessPolicy = "all"      // Every process that ran has to exit successfully
+	SuccessFirst         SuccessPolicy = "first"    // The first process to exit decides the exit code
+	SuccessLast          SuccessPolicy = "last"     // The last process to exit decides the exit code
+	SuccessCommandPrefix string        = "command:" // The named process decides the exit code (command:<name|prefix|index>)
+)
+
+// Returns the success policy, all if it is not set
+func (c *Config) GetSuccessPolicy() SuccessPolicy {
+	if c.Success == "" {
+		return SuccessAll
+	}
+	return c.Success
+}
+
+// Returns the process named by a command:<name|prefix|index> policy
+func (s SuccessPolicy) command() (string, bool) {
+	return strings.CutPrefix(string(s), SuccessCommandPrefix)
+}
+
+// Validates the success policy, command policies have to name an existing process
+func (s SuccessPolicy) Validate(contexts []*ExecutionContext) error {
+	switch s {
+	case SuccessAll, SuccessFirst, SuccessLast:
+		return nil
+	}
+	target, isCommand := s.command()
+	if !isCommand {
+		return errors.New("unknown success policy \"" + string(s) + "\" -- all, first, last or command:<name> supported")
+	}
+	if findContext(target, contexts) == nil {
+		return errors.New("success policy process does not exist, Non existant process = " + target)
+	}
+	return nil
+}
+
+// Finds the context by name, prefix or index
+func findContext(target string, contexts []*ExecutionContext) *ExecutionContext {
+	for _, context := range contexts {
+		if context.Process.Name == target || context.Process.Prefix == target {
+			return context
+		}
+	}
+	if index, err := strconv.Atoi(target); err == nil && index >= 0 && index < len(contexts) {
+		return contexts[index]
+	}
+	return nil
+}
+
+// Converts a process exit code to an exit code process party can exit with
+func toExitCode(code int) int {
+	if code < 0 {
+		// Killed by a signal or never started
+		return 1
+	}
+	return code
+}
+
+// Returns the exit code the last execution of the context decides, 0 if it is one of its success exit codes
+func (e *ExecutionContext) policyExitCode() int {
+	code := e.GetExitCode()
+	if e.Process.IsSuccessExitCode(code) {
+		return 0
+	}
+	return toExitCode(code)
+}
+
+// Returns when the last execution of the context ended, zero if it never ran
+func (e *ExecutionContext) lastExit() time.Time {
+	e.executionMutex.RLock()
+	defer e.executionMutex.RUnlock()
+	return e.exitTime
+}
+
+// Returns true if the last execution was stopped by a buzzkill, e.g. process party shutting down on ctrl+c
+func (e *ExecutionContext) buzzkilled() bool {
+	return e.getExitEvent() == ExitEventBuzzkilled
+}
+
+// Returns the exit code process party should exit with once all contexts ended.
+// Processes that never ran (e.g. waiting for a trigger) and processes stopped by a buzzkill or shutdown are not considered
+func (s SuccessPolicy) ExitCode(contexts []*ExecutionContext) int {
+	if target, isCommand := s.command(); isCommand {
+		context := findContext(target, contexts)
+		if context == nil || context.lastExit().IsZero() {
+			return 1
+		}
+		if context.buzzkilled() {
+			return 0
+		}
+		return context.policyExitCode()
+	}
+
+	var chosen *ExecutionContext
+	for _, context := range contexts {
+		exitTime := context.lastExit()
+		if exitTime.IsZero() || context.buzzkilled() {
+			continue
+		}
+		switch s {
+		case SuccessFirst:
+			if chosen == nil || exitTime.Before(chosen.lastExit()) {
+				chosen = context
+			}
+		case SuccessLast:
+			if chosen == nil || exitTime.After(chosen.lastExit()) {
+				chosen = context
+			}
+		default:
+			// Every process has to succeed, report the first failure
+			if !context.Process.IsSuccessExitCode(context.GetExitCode()) && (chosen == nil || exitTime.Before(chosen.lastExit())) {
+				chosen = context
+			}
+		}
+	}
+
+	if chosen == nil {
+		return 0
+	}
+	return chosen.policyExitCode()
+}
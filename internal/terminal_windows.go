@@ -0,0 +1,48 @@
+package pp
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Returns true if the file is a console. Character devices such as NUL are not consoles
+func IsTerminal(file *os.File) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(file.Fd()), &mode) == nil
+}
+
+// Puts the console into raw mode with virtual terminal sequences enabled, returns a function restoring the previous mode
+func makeRawTerminal(file *os.File) (func() error, error) {
+	input := windows.Handle(file.Fd())
+	output := windows.Handle(os.Stdout.Fd())
+	var inputMode, outputMode uint32
+	if err := windows.GetConsoleMode(input, &inputMode); err != nil {
+		return nil, err
+	}
+	if err := windows.GetConsoleMode(output, &outputMode); err != nil {
+		return nil, err
+	}
+
+	raw := inputMode &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT)
+	if err := windows.SetConsoleMode(input, raw|windows.ENABLE_VIRTUAL_TERMINAL_INPUT); err != nil {
+		return nil, err
+	}
+	if err := windows.SetConsoleMode(output, outputMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		windows.SetConsoleMode(input, inputMode)
+		return nil, err
+	}
+	return func() error {
+		windows.SetConsoleMode(output, outputMode)
+		return windows.SetConsoleMode(input, inputMode)
+	}, nil
+}
+
+// Returns the width and height of the console window in characters
+func terminalSize(file *os.File) (int, int, error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stdout.Fd()), &info); err != nil {
+		return 0, 0, err
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, nil
+}
@@ -11,46 +11,288 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-// Returns true if the trigger should run, false if it should not
-func (c *ExecutionContext) fileFilter() func(string) bool {
-	return func(path string) bool {
-		// First check exact matches in included items
-		for _, item := range c.Process.Trigger.FileSystem.Watch {
-			if item == path {
-				return true
-			}
+// Decides which paths trigger the process and which directories are watched. Only used by the goroutine of the watcher
+type fsFilter struct {
+	roots     []string // Watched directories, patterns are matched relative to the deepest root containing the path
+	watch     []string
+	ignore    []string
+	ignores   globList
+	filters   globList
+	gitignore bool
+	rules     []ignoreRule // Patterns of the ignore files in the watched directories
+	files     []string     // Watched files, their directories are watched without matching the other files in them
+	dirs      []string     // Watched directories
+}
+
+// Operations of fs events that can trigger the process
+var fsEvents = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+	"chmod":  fsnotify.Chmod,
+}
+
+// Milliseconds without changes before an fs trigger fires if debounce_ms is not set
+const DefaultDebounceTime = 50
+
+// Returns how long no file has to change before the trigger fires, an explicit 0 fires on every change
+func (t *FileSystemTrigger) GetDebounceTime() time.Duration {
+	if t.DebounceTime == nil {
+		return DefaultDebounceTime * time.Millisecond
+	}
+	return time.Duration(*t.DebounceTime) * time.Millisecond
+}
+
+// Operations that trigger the process if no events are configured, chmod is left out as editors and touch produce it constantly
+const defaultFsEvents = fsnotify.Create | fsnotify.Write | fsnotify.Remove | fsnotify.Rename
+
+// Operations of atomic saves, where editors replace the file by renaming or removing it and creating it again
+const atomicSaveOps = fsnotify.Create | fsnotify.Remove | fsnotify.Rename
+
+// Temporary files of editors, ignored unless they are included again by an ignore pattern
+var editorTempFiles = []string{"*~", "*.swp", "*.swx", "*.swo", "4913", "*___jb_tmp___", "*___jb_old___"}
+
+// Returns the operations that trigger the process
+func (t *FileSystemTrigger) GetEvents() (fsnotify.Op, error) {
+	if len(t.Events) == 0 {
+		return defaultFsEvents, nil
+	}
+	var events fsnotify.Op
+	for _, name := range t.Events {
+		op, exists := fsEvents[strings.ToLower(name)]
+		if !exists {
+			return 0, fmt.Errorf("unknown event %s, use create, write, remove, rename or chmod", name)
 		}
+		events |= op
+	}
+	return events, nil
+}
 
-		// Then check exact matches in excluded items
-		for _, item := range c.Process.Trigger.FileSystem.Ignore {
-			if item == path {
-				return false
-			}
+// File changed since the last run of an fs trigger
+type FileChange struct {
+	Path string `json:"path"` // Absolute path of the file
+	Op   string `json:"op"`   // Operations on the file, e.g. CREATE|WRITE
+}
+
+// Changes collected by an fs trigger until it fires, in the order the files first changed
+type fsChanges struct {
+	files    []FileChange
+	relative []string // Paths relative to the watched directories, shown in the message
+	ops      []fsnotify.Op
+	index    map[string]int
+}
+
+// Number of changed files listed in the trigger message, the rest are counted
+const fsChangesListed = 5
+
+// Adds an operation on the file, operations on a file that already changed are combined
+func (c *fsChanges) add(relative string, path string, op fsnotify.Op) {
+	if c.index == nil {
+		c.index = map[string]int{}
+	}
+	i, exists := c.index[path]
+	if !exists {
+		i = len(c.files)
+		c.index[path] = i
+		c.files = append(c.files, FileChange{Path: path})
+		c.relative = append(c.relative, relative)
+		c.ops = append(c.ops, 0)
+	}
+	c.ops[i] |= op
+	c.files[i].Op = c.ops[i].String()
+}
+
+// Describes the changes, listing the first files
+func (c *fsChanges) message() string {
+	if len(c.files) == 1 {
+		return fmt.Sprintf("FS trigger captured - %s	%s", c.files[0].Op, c.relative[0])
+	}
+	listed := []string{}
+	for i := range c.files {
+		if i == fsChangesListed {
+			listed = append(listed, fmt.Sprintf("and %d more", len(c.files)-fsChangesListed))
+			break
 		}
+		listed = append(listed, c.files[i].Op+" "+c.relative[i])
+	}
+	return fmt.Sprintf("FS trigger captured - %d changes: %s", len(c.files), strings.Join(listed, ", "))
+}
 
-		// Check if path matches any of the exclude patterns
-		for _, pattern := range c.Process.Trigger.FileSystem.Ignore {
-			matched, err := filepath.Match(pattern, filepath.Base(path))
-			if err == nil && matched {
-				return false
+// Returns the changes with the operations in events. Files that were renamed or removed and created again,
+// as editors do when saving atomically, are written if they exist
+func (c *fsChanges) filter(events fsnotify.Op) fsChanges {
+	filtered := fsChanges{}
+	for i, file := range c.files {
+		op := c.ops[i]
+		if op.Has(fsnotify.Create) && op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+			if _, err := os.Stat(file.Path); err == nil {
+				op = op&^atomicSaveOps | fsnotify.Write
 			}
 		}
+		if op&events != 0 {
+			filtered.add(c.relative[i], file.Path, op&events)
+		}
+	}
+	return filtered
+}
 
-		// Finally, check if path matches any of the include patterns
-		for _, pattern := range c.Process.Trigger.FileSystem.ContainFilters {
-			matched, err := filepath.Match(pattern, filepath.Base(path))
-			if err == nil && matched {
-				return true
+// Replaced by the files changed since the last run of an fs trigger in the command and arguments
+const ChangedFilesTemplate = "{{changed_files}}"
+
+// Environment variables describing the trigger that started the run
+const (
+	EnvTriggerSource = "PP_TRIGGER_SOURCE" // Kind of trigger (fs, process or manual), empty if the run was not triggered
+	EnvChangedFiles  = "PP_CHANGED_FILES"  // Changed files, one per line
+	EnvChangedEvents = "PP_CHANGED_EVENTS" // Operations and changed files, one "OP path" per line
+)
+
+// Returns the paths of the changed files
+func (m TriggerMessage) changedFiles() []string {
+	files := make([]string, 0, len(m.Changes))
+	for _, change := range m.Changes {
+		files = append(files, change.Path)
+	}
+	return files
+}
+
+// Returns the variables describing the trigger. They are always set, so runs that were not triggered
+// do not inherit them from process party
+func (m TriggerMessage) environment() []string {
+	events := make([]string, 0, len(m.Changes))
+	for _, change := range m.Changes {
+		events = append(events, change.Op+" "+change.Path)
+	}
+	return []string{
+		EnvTriggerSource + "=" + m.Kind,
+		EnvChangedFiles + "=" + strings.Join(m.changedFiles(), "\n"),
+		EnvChangedEvents + "=" + strings.Join(events, "\n"),
+	}
+}
+
+// Replaces {{changed_files}} in the arguments. An argument that only contains the template is replaced
+// by an argument per file, otherwise the files are joined by spaces
+func expandChangedFiles(args []string, files []string) []string {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg == ChangedFilesTemplate:
+			expanded = append(expanded, files...)
+		case strings.Contains(arg, ChangedFilesTemplate):
+			expanded = append(expanded, strings.ReplaceAll(arg, ChangedFilesTemplate, strings.Join(files, " ")))
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded
+}
+
+// Creates the filter of the fs trigger, the patterns are matched relative to the roots
+func (c *ExecutionContext) createFsFilter(roots []string) (*fsFilter, error) {
+	ignores, err := parseGlobs(append(editorTempFiles, c.Process.Trigger.FileSystem.Ignore...))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+	filters, err := parseGlobs(c.Process.Trigger.FileSystem.ContainFilters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter_for pattern: %w", err)
+	}
+	return &fsFilter{
+		roots:     roots,
+		watch:     c.Process.Trigger.FileSystem.Watch,
+		ignore:    c.Process.Trigger.FileSystem.Ignore,
+		ignores:   ignores,
+		filters:   filters,
+		gitignore: c.Process.Trigger.FileSystem.Gitignore,
+	}, nil
+}
+
+// Returns the slash separated path relative to the deepest root containing it
+func (f *fsFilter) relative(path string) string {
+	root := ""
+	for _, item := range f.roots {
+		if (path == item || strings.HasPrefix(path, item+string(os.PathSeparator))) && len(item) > len(root) {
+			root = item
+		}
+	}
+	if root == "" {
+		return filepath.Base(path)
+	}
+	relative, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(relative)
+}
+
+// Returns true if the path is in a watched directory
+func (f *fsFilter) inWatchedDirectory(path string) bool {
+	for _, dir := range f.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the path is ignored by the ignore files or the ignore patterns, which can include paths again
+func (f *fsFilter) ignored(path string, isDir bool) bool {
+	// Exact matches in excluded items
+	if contains(f.ignore, path) {
+		return true
+	}
+	// Other files in the directories of watched files
+	if len(f.files) > 0 && !contains(f.files, path) && !f.inWatchedDirectory(path) {
+		return true
+	}
+
+	ignored := false
+	if f.gitignore {
+		if filepath.Base(path) == ".git" {
+			ignored = true
+		}
+		// Rules of parent directories are read first, so deeper ignore files take precedence
+		for _, rule := range f.rules {
+			if !strings.HasPrefix(path, rule.directory+string(os.PathSeparator)) {
+				continue
+			}
+			relative, err := filepath.Rel(rule.directory, path)
+			if err == nil && rule.pattern.matches(filepath.ToSlash(relative), isDir) {
+				ignored = !rule.pattern.negate
 			}
 		}
+	}
+	return f.ignores.apply(ignored, f.relative(path), isDir)
+}
+
+// Returns true if the trigger should run, false if it should not
+func (f *fsFilter) matches(path string) bool {
+	// First check exact matches in included items
+	if contains(f.watch, path) {
+		return true
+	}
+
+	isDir := false
+	if stat, err := os.Stat(path); err == nil {
+		isDir = stat.IsDir()
+	}
+	if f.ignored(path, isDir) {
+		return false
+	}
+
+	// If we are looking for specific files only matching files are included, filters with only negated patterns include the rest
+	return f.filters.apply(!f.filters.hasPositive(), f.relative(path), isDir)
+}
 
-		// If we are looking for specific files and we reach here we did not find any, else we can use it
-		return len(c.Process.Trigger.FileSystem.ContainFilters) == 0
+// Reads the ignore files of the directory if they are honoured
+func (f *fsFilter) addIgnoreFiles(directory string) {
+	if f.gitignore {
+		f.rules = append(f.rules, readIgnoreFiles(directory)...)
 	}
 }
 
-// Recursivley watches directories if enabled
-func (c *ExecutionContext) watch(path string, watcher *fsnotify.Watcher) error {
+// Recursivley watches directories if enabled, ignored directories are not watched
+func (c *ExecutionContext) watch(path string, watcher *fsnotify.Watcher, filter *fsFilter) error {
 
 	err := watcher.Add(path)
 	if err != nil {
@@ -61,12 +303,17 @@ func (c *ExecutionContext) watch(path string, watcher *fsnotify.Watcher) error {
 	// If the entry is a folder recursivley call watch if enabled
 	dirs, err := os.ReadDir(path)
 	if err == nil {
+		filter.addIgnoreFiles(path)
 		for _, dir := range dirs {
 			if dir.Type().IsDir() {
 				if c.Process.Trigger.FileSystem.NonRecursive {
 					return nil
 				}
-				err := c.watch(filepath.Join(path, dir.Name()), watcher)
+				child := filepath.Join(path, dir.Name())
+				if filter.ignored(child, true) {
+					continue
+				}
+				err := c.watch(child, watcher, filter)
 				if err != nil {
 					return nil
 				}
@@ -78,7 +325,7 @@ func (c *ExecutionContext) watch(path string, watcher *fsnotify.Watcher) error {
 }
 
 // Checks if the fs event was a creation event and if the item is a directory. If it is it adds it to the watcher
-func (c *ExecutionContext) recursivelyWatchCreatedEvent(event fsnotify.Event, watcher *fsnotify.Watcher) {
+func (c *ExecutionContext) recursivelyWatchCreatedEvent(event fsnotify.Event, watcher *fsnotify.Watcher, filter *fsFilter) {
 	if c.Process.Trigger.FileSystem.NonRecursive {
 		return
 	}
@@ -99,16 +346,28 @@ func (c *ExecutionContext) recursivelyWatchCreatedEvent(event fsnotify.Event, wa
 						c.errorWriter.Write([]byte("Invalid path: " + event.Name))
 						return
 					}
+					if filter.ignored(absPath, true) {
+						return
+					}
 					c.infoWriter.Printf("A new subdirectory was created in FS watcher, monitoring %s", absPath)
-					c.watch(absPath, watcher)
+					c.watch(absPath, watcher, filter)
 				}
 			}
 		}
 	}
 }
 
+// Resolves a watch path, relative paths resolve against the process working directory if set
+func (c *ExecutionContext) resolveWatchPath(path string) (string, error) {
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) && c.Process.Cwd != "" {
+		path = filepath.Join(c.Process.Cwd, path)
+	}
+	return filepath.Abs(path)
+}
+
 // This creates a trigger that watches any directories and recursive subdirectories
-func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
+func (c *ExecutionContext) CreateFsTrigger() (chan TriggerMessage, error) {
 
 	if len(c.Process.Trigger.FileSystem.Watch) <= 0 {
 		return nil, nil
@@ -125,10 +384,40 @@ func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
 		return nil, errors.New("Restarting triggered processes can lead to undesired behaviour. Remove triggers or restart attempts on process [" + c.Process.Name + "]")
 	}
 
+	events, err := c.Process.Trigger.FileSystem.GetEvents()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	// Patterns are matched relative to the watched directories, or the directories of watched files
 	addedPaths := []string{}
+	roots := []string{}
+	files := []string{}
+	dirs := []string{}
+	for _, item := range c.Process.Trigger.FileSystem.Watch {
+		absPath, err := c.resolveWatchPath(item)
+		if err != nil {
+			continue
+		}
+		if stat, err := os.Stat(absPath); err == nil && !stat.IsDir() {
+			files = append(files, absPath)
+			absPath = filepath.Dir(absPath)
+		} else {
+			dirs = append(dirs, absPath)
+		}
+		roots = append(roots, absPath)
+	}
+	filter, err := c.createFsFilter(roots)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	filter.files = files
+	filter.dirs = dirs
 
 	for _, item := range c.Process.Trigger.FileSystem.Watch {
-		absPath, err := filepath.Abs(filepath.Clean(item))
+		absPath, err := c.resolveWatchPath(item)
 		if err != nil {
 			c.errorWriter.Write([]byte("Invalid path: " + item))
 			return nil, err
@@ -141,7 +430,12 @@ func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
 
 		c.infoWriter.Printf("Monitoring path: %s", absPath)
 
-		err = c.watch(absPath, watcher)
+		if contains(files, absPath) {
+			// Watch the directory of the file, as the watch of the file is lost when an editor replaces it
+			err = watcher.Add(filepath.Dir(absPath))
+		} else {
+			err = c.watch(absPath, watcher, filter)
+		}
 
 		if err != nil {
 			c.errorWriter.Write([]byte("File/Directory does not exist: " + item))
@@ -151,16 +445,41 @@ func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
 		addedPaths = append(addedPaths, absPath)
 	}
 
-	trigger := make(chan string)
-	filter := c.fileFilter()
+	trigger := make(chan TriggerMessage)
 	exitChannel := c.getInternalExitNotifier()
 
 	// Start file watcher
 	go func() {
 		defer close(trigger)
 
-		debounceTimer := time.Now()
-		debounceTime := c.Process.Trigger.FileSystem.DebounceTime //  ms
+		// Changes are collected until no event matched for the debounce time, or the max wait passed since the first change
+		quietPeriod := c.Process.Trigger.FileSystem.GetDebounceTime()
+		maxWait := time.Duration(c.Process.Trigger.FileSystem.MaxWait) * time.Millisecond
+		changes := fsChanges{}
+		quietTimer := time.NewTimer(quietPeriod)
+		quietTimer.Stop()
+		maxWaitTimer := time.NewTimer(maxWait)
+		maxWaitTimer.Stop()
+
+		// Sends the collected changes, returns false if the process exited before the trigger was received
+		send := func() bool {
+			quietTimer.Stop()
+			maxWaitTimer.Stop()
+			filtered := changes.filter(events)
+			changes = fsChanges{}
+			if len(filtered.files) == 0 {
+				return true
+			}
+			message := TriggerMessage{Kind: TriggerKindFs, Message: filtered.message(), Changes: filtered.files}
+			select {
+			case trigger <- message:
+				return true
+			case <-exitChannel:
+				c.infoWriter.Printf("Process exiting, closing FS watcher")
+				watcher.Close()
+				return false
+			}
+		}
 
 		for {
 			select {
@@ -170,15 +489,25 @@ func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
 					watcher.Close()
 					return
 				}
-				if filter(event.Name) {
-					c.recursivelyWatchCreatedEvent(event, watcher)
-					if time.Since(debounceTimer) > time.Duration(debounceTime)*time.Millisecond {
-						filepath := strings.Split(event.Name, string(os.PathSeparator))
-						trigger <- fmt.Sprintf("FS trigger captured - %s	%s", event.Op, filepath[len(filepath)-1])
-						debounceTimer = time.Now()
+				// Created directories are watched even if they do not match the filters, unless they are ignored
+				c.recursivelyWatchCreatedEvent(event, watcher, filter)
+				// Operations that are not triggering are still collected for atomic saves
+				if event.Op&(events|atomicSaveOps) != 0 && filter.matches(event.Name) {
+					if len(changes.files) == 0 && maxWait > 0 {
+						maxWaitTimer.Reset(maxWait)
 					}
+					changes.add(filter.relative(event.Name), event.Name, event.Op)
+					quietTimer.Reset(quietPeriod)
 				}
 
+			case <-quietTimer.C:
+				if !send() {
+					return
+				}
+			case <-maxWaitTimer.C:
+				if !send() {
+					return
+				}
 			case err, ok := <-watcher.Errors:
 				c.errorWriter.Write([]byte(fmt.Sprintf("An unexpected error occured, %s", err.Error())))
 				if !ok {
@@ -195,22 +524,32 @@ func (c *ExecutionContext) CreateFsTrigger() (chan string, error) {
 }
 
 // Creates a channel that runs when the contexts emits the listening signal
-func (e *ExecutionContext) CreateProcessTrigger(signal ProcessStatus, message string) chan string {
-	trigger := make(chan string)
+func (e *ExecutionContext) CreateProcessTrigger(signal ProcessStatus, message string) chan TriggerMessage {
+	trigger := make(chan TriggerMessage)
+	// Listen before returning so that no status can be missed
+	exitChannel := e.getInternalExitNotifier()
+	sigChannel := e.GetProcessNotificationChannel()
 
 	go func() {
-		exitChannel := e.getInternalExitNotifier()
-		sigChannel := e.GetProcessNotificationChannel()
 	monitorLoop:
 		for {
 			select {
-			case sig := <-sigChannel:
+			case sig, ok := <-sigChannel:
+				if !ok {
+					close(trigger)
+					break monitorLoop
+				}
 				if signal == sig {
 					if trigger != nil {
-						trigger <- message
+						trigger <- TriggerMessage{Kind: TriggerKindProcess, Message: message}
 					}
 				}
-			case <-exitChannel:
+			case _, ok := <-exitChannel:
+				if !ok {
+					// The process ended, deliver the remaining statuses until the status channel closes
+					exitChannel = nil
+					break
+				}
 				close(trigger)
 				break monitorLoop
 			}
@@ -230,8 +569,42 @@ func contains(arr []string, target string) bool {
 	return false
 }
 
+// Returns the trigger loop in the contexts as a path of process names, or nil if there is none.
+// Processes that allow loops are not checked, as every loop through them has to trigger them
+func findTriggerLoop(contexts []*ExecutionContext) []string {
+	exists := map[string]bool{}
+	names := []string{}
+	for _, context := range contexts {
+		exists[context.Process.Name] = true
+		names = append(names, context.Process.Name)
+	}
+
+	// Each edge points from the process emitting the status to the process it triggers
+	edges := map[string][]string{}
+	for _, context := range contexts {
+		trigger := context.Process.Trigger.Process
+		if trigger.AllowLoop {
+			continue
+		}
+		for _, sources := range [][]string{trigger.OnStart, trigger.OnComplete, trigger.OnError} {
+			for _, source := range sources {
+				if exists[source] && !contains(edges[source], context.Process.Name) {
+					edges[source] = append(edges[source], context.Process.Name)
+				}
+			}
+		}
+	}
+
+	return findCycle(names, edges)
+}
+
 // Links process triggers together for a range of execution contexts
 func LinkProcessTriggers(contexts []*ExecutionContext) error {
+	// Check for loops before creating any triggers
+	if loop := findTriggerLoop(contexts); loop != nil {
+		return errors.New("Circular trigger detected: " + strings.Join(loop, " -> ") + " - set allow_loop on a process in the loop to allow it")
+	}
+
 	// Filesystem triggers
 	for _, context := range contexts {
 		fsTrigger, err := context.CreateFsTrigger()
@@ -249,7 +622,7 @@ func LinkProcessTriggers(contexts []*ExecutionContext) error {
 
 	// Process triggers
 
-	// Create a map for quick access and checking circular triggers
+	// Create a map for quick access
 	x := map[string]*ExecutionContext{}
 	for _, context := range contexts {
 		x[context.Process.Name] = context
@@ -258,9 +631,6 @@ func LinkProcessTriggers(contexts []*ExecutionContext) error {
 	applyTriggers := func(triggers []string, signal ProcessStatus, context *ExecutionContext) error {
 		monitoredProcesses := []string{}
 		for _, process := range triggers {
-			if process == context.Process.Name {
-				return errors.New("Circular trigger detected: " + process + " canot depend on itself")
-			}
 			if contains(monitoredProcesses, process) {
 				context.errorWriter.Printf("Duplicate trigger process: \"%s\" - not monitoring twice", process)
 				continue
@@ -269,15 +639,6 @@ func LinkProcessTriggers(contexts []*ExecutionContext) error {
 			monitoredProcesses = append(monitoredProcesses, process)
 
 			if value, exists := x[process]; exists {
-				if contains(value.Process.Trigger.Process.OnComplete, process) {
-					return errors.New("Circular trigger detected: " + value.Process.Name + " and " + process + " trigger each other")
-				}
-				if contains(value.Process.Trigger.Process.OnError, process) {
-					return errors.New("Circular trigger detected: " + value.Process.Name + " and " + process + " trigger each other")
-				}
-				if contains(value.Process.Trigger.Process.OnStart, process) {
-					return errors.New("Circular trigger detected: " + value.Process.Name + " and " + process + " trigger each other")
-				}
 				trigger := value.CreateProcessTrigger(signal, fmt.Sprintf("[%s] triggered a run", process))
 				context.triggers = append(context.triggers, trigger)
 			} else {
@@ -0,0 +1,118 @@
+package pp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that operations on a file are combined and the message lists the first files
+func TestFsChanges(t *testing.T) {
+	t.Parallel()
+
+	changes := fsChanges{}
+	changes.add("src/main.go", "/project/src/main.go", fsnotify.Write)
+	assert.Equal(t, "FS trigger captured - WRITE	src/main.go", changes.message())
+
+	changes.add("src/api.go", "/project/src/api.go", fsnotify.Create)
+	changes.add("src/api.go", "/project/src/api.go", fsnotify.Write)
+	changes.add("src/main.go", "/project/src/main.go", fsnotify.Write)
+	assert.Equal(t, []FileChange{
+		{Path: "/project/src/main.go", Op: "WRITE"},
+		{Path: "/project/src/api.go", Op: "CREATE|WRITE"},
+	}, changes.files)
+	assert.Equal(t, "FS trigger captured - 2 changes: WRITE src/main.go, CREATE|WRITE src/api.go", changes.message())
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		changes.add(name, "/project/"+name, fsnotify.Remove)
+	}
+	assert.Equal(t, "FS trigger captured - 7 changes: WRITE src/main.go, CREATE|WRITE src/api.go, REMOVE a, REMOVE b, REMOVE c, and 2 more", changes.message())
+}
+
+// Ensure that {{changed_files}} is replaced by the changed files in the command and arguments
+func TestChangedFilesTemplate(t *testing.T) {
+	t.Parallel()
+
+	trigger := TriggerMessage{Kind: TriggerKindFs, Changes: []FileChange{
+		{Path: "/project/main.go", Op: "WRITE"},
+		{Path: "/project/my api.go", Op: "CREATE|WRITE"},
+	}}
+	assert.Equal(t, []string{"--fix", "/project/main.go", "/project/my api.go", "--files=/project/main.go /project/my api.go"},
+		expandChangedFiles([]string{"--fix", "{{changed_files}}", "--files={{changed_files}}"}, trigger.changedFiles()))
+	assert.Equal(t, []string{"--fix", "--files="}, expandChangedFiles([]string{"--fix", "{{changed_files}}", "--files={{changed_files}}"}, nil))
+
+	process := &Process{Command: "eslint", Args: []string{"{{changed_files}}"}}
+	assert.Equal(t, []string{"eslint", "/project/main.go", "/project/my api.go"}, process.createCommand(trigger).Args)
+	assert.Equal(t, []string{"eslint"}, process.createCommand(TriggerMessage{}).Args)
+
+	shell := true
+	process = &Process{Command: "eslint {{changed_files}} &&", Args: []string{"echo", "{{changed_files}}"}, Shell: &shell}
+	commandLine := process.createCommand(trigger).Args[2]
+	assert.Equal(t, "eslint "+joinShellArgs(trigger.changedFiles())+" && "+joinShellArgs(append([]string{"echo"}, trigger.changedFiles()...)), commandLine)
+}
+
+// Ensure that the environment describes the trigger and is set for runs that were not triggered
+func TestTriggerEnvironment(t *testing.T) {
+	t.Parallel()
+
+	trigger := TriggerMessage{Kind: TriggerKindFs, Changes: []FileChange{
+		{Path: "/project/main.go", Op: "WRITE"},
+		{Path: "/project/api.go", Op: "CREATE|WRITE"},
+	}}
+	assert.Equal(t, []string{
+		"PP_TRIGGER_SOURCE=fs",
+		"PP_CHANGED_FILES=/project/main.go\n/project/api.go",
+		"PP_CHANGED_EVENTS=WRITE /project/main.go\nCREATE|WRITE /project/api.go",
+	}, trigger.environment())
+	assert.Equal(t, []string{"PP_TRIGGER_SOURCE=", "PP_CHANGED_FILES=", "PP_CHANGED_EVENTS="}, TriggerMessage{}.environment())
+}
+
+// Ensure that the events are parsed and chmod is left out by default
+func TestFsEvents(t *testing.T) {
+	t.Parallel()
+
+	events, err := (&FileSystemTrigger{}).GetEvents()
+	assert.Nil(t, err)
+	assert.Equal(t, fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename, events)
+	assert.False(t, events.Has(fsnotify.Chmod))
+
+	events, err = (&FileSystemTrigger{Events: []string{"write", "CHMOD"}}).GetEvents()
+	assert.Nil(t, err)
+	assert.Equal(t, fsnotify.Write|fsnotify.Chmod, events)
+
+	_, err = (&FileSystemTrigger{Events: []string{"write", "modify"}}).GetEvents()
+	assert.NotNil(t, err)
+}
+
+// Ensure that changes are filtered by the events and atomic saves are written
+func TestFsChangesFilter(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	saved := filepath.Join(root, "saved.go")
+	assert.Nil(t, os.WriteFile(saved, []byte("package main"), 0644))
+
+	changes := fsChanges{}
+	// Vim renames the file to a backup and writes a new file, JetBrains editors rename a temporary file over it
+	changes.add("saved.go", saved, fsnotify.Rename)
+	changes.add("saved.go", saved, fsnotify.Create)
+	changes.add("saved.go", saved, fsnotify.Write)
+	changes.add("deleted.go", filepath.Join(root, "deleted.go"), fsnotify.Remove)
+	changes.add("touched.go", filepath.Join(root, "touched.go"), fsnotify.Chmod)
+
+	filtered := changes.filter(defaultFsEvents)
+	assert.Equal(t, []FileChange{
+		{Path: saved, Op: "WRITE"},
+		{Path: filepath.Join(root, "deleted.go"), Op: "REMOVE"},
+	}, filtered.files)
+
+	filtered = changes.filter(fsnotify.Write)
+	assert.Equal(t, []FileChange{{Path: saved, Op: "WRITE"}}, filtered.files)
+	assert.Equal(t, "FS trigger captured - WRITE	saved.go", filtered.message())
+
+	filtered = changes.filter(fsnotify.Chmod)
+	assert.Equal(t, []FileChange{{Path: filepath.Join(root, "touched.go"), Op: "CHMOD"}}, filtered.files)
+}
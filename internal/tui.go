@@ -0,0 +1,572 @@
+package pp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	TuiScrollback    = 10000 // Lines of output kept for every view
+	tuiSidebarWidth  = 34
+	tuiFrameInterval = 50 * time.Millisecond
+)
+
+// Terminal escape sequences used to draw the TUI
+const (
+	ansiReset          = "\x1b[0m"
+	ansiBold           = "\x1b[1m"
+	ansiDim            = "\x1b[2m"
+	ansiReverse        = "\x1b[7m"
+	ansiRed            = "\x1b[31m"
+	ansiGreen          = "\x1b[32m"
+	ansiYellow         = "\x1b[33m"
+	ansiClearLine      = "\x1b[K"
+	ansiHome           = "\x1b[H"
+	ansiEnterAltScreen = "\x1b[?1049h\x1b[?25l"
+	ansiLeaveAltScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// Escape sequences written by processes, removed as the TUI draws its own colours
+var ansiSequence = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|.)`)
+
+type (
+	logLine struct {
+		process int // Index of the context that wrote the line
+		text    string
+		isError bool
+		time    time.Time // When the line was written (only kept by the process history)
+	}
+
+	// Keeps the most recent lines of output, dropping the oldest lines once full
+	logBuffer struct {
+		lines []logLine
+		start int
+		limit int
+	}
+
+	// Receives the output of a single context and adds it to the TUI line by line
+	tuiWriter struct {
+		tui     *Tui
+		process int
+		isError bool
+	}
+
+	// Full screen terminal interface with a process list and a log pane with scrollback for every process.
+	// The first view combines the output of every process
+	Tui struct {
+		contexts      []*ExecutionContext
+		mutex         sync.Mutex
+		logs          []*logBuffer // Output of every view, the combined view followed by every process
+		scroll        []int        // Lines every view is scrolled up from the bottom
+		selected      int          // Selected view
+		search        []string     // Search of every view, only lines containing it are shown
+		editingSearch bool
+		message       string // Feedback shown in the status bar
+		dirty         atomic.Bool
+		quit          chan struct{}
+		kill          chan struct{}
+		quitRequests  int
+		done          chan struct{}
+		restore       func() error
+		stopOnce      sync.Once
+	}
+)
+
+func newLogBuffer(limit int) *logBuffer {
+	return &logBuffer{lines: make([]logLine, 0), limit: limit}
+}
+
+// Adds the line, dropping the oldest line once the buffer is full
+func (b *logBuffer) append(line logLine) {
+	if len(b.lines) < b.limit {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % b.limit
+}
+
+// Returns the number of lines in the buffer
+func (b *logBuffer) len() int {
+	return len(b.lines)
+}
+
+// Returns the line at the index, where 0 is the oldest line
+func (b *logBuffer) get(index int) logLine {
+	return b.lines[(b.start+index)%len(b.lines)]
+}
+
+// Removes escape sequences and control characters so the line can be drawn in a single row
+func cleanLine(line string) string {
+	line = ansiSequence.ReplaceAllString(line, "")
+	line = strings.ReplaceAll(line, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// Pads or cuts the text to exactly the width
+func fitWidth(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+func (w *tuiWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		w.tui.addLine(logLine{process: w.process, text: cleanLine(line), isError: w.isError})
+	}
+	return len(p), nil
+}
+
+// Creates the TUI for the contexts and sends their output to it, has to be called before the contexts are started
+func CreateTui(contexts []*ExecutionContext) *Tui {
+	t := &Tui{
+		contexts: contexts,
+		logs:     []*logBuffer{newLogBuffer(TuiScrollback)},
+		scroll:   make([]int, len(contexts)+1),
+		search:   make([]string, len(contexts)+1),
+		quit:     make(chan struct{}),
+		kill:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for index, context := range contexts {
+		t.logs = append(t.logs, newLogBuffer(TuiScrollback))
+		context.SetOutput(&tuiWriter{tui: t, process: index}, &tuiWriter{tui: t, process: index, isError: true})
+	}
+	return t
+}
+
+// Adds a line to the view of its process and to the combined view
+func (t *Tui) addLine(line logLine) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, view := range []int{0, line.process + 1} {
+		t.logs[view].append(line)
+		// Keep scrolled views on the same lines
+		if t.scroll[view] > 0 && t.matches(view, line) {
+			t.scroll[view]++
+		}
+	}
+	t.dirty.Store(true)
+}
+
+// Returns true if the line contains the search of the view
+func (t *Tui) matches(view int, line logLine) bool {
+	search := t.search[view]
+	if search == "" {
+		return true
+	}
+	start, _ := indexFold(line.text, search)
+	return start >= 0
+}
+
+// Returns the byte offsets of the first case-insensitive match of the search in the text, or -1 if there is none.
+// Runes are compared one by one, lowercasing the text could change its length and move the offsets
+func indexFold(text string, search string) (int, int) {
+	for start := range text {
+		end := start
+		matched := true
+		for _, want := range search {
+			got, size := utf8.DecodeRuneInString(text[end:])
+			if size == 0 || !strings.EqualFold(string(got), string(want)) {
+				matched = false
+				break
+			}
+			end += size
+		}
+		if matched {
+			return start, end
+		}
+	}
+	return -1, -1
+}
+
+// Shows the message in the status bar
+func (t *Tui) Message(format string, a ...any) {
+	t.mutex.Lock()
+	t.message = fmt.Sprintf(format, a...)
+	t.mutex.Unlock()
+	t.dirty.Store(true)
+}
+
+// Returns a channel that is closed once quitting is requested (q or ctrl+c)
+func (t *Tui) Quit() <-chan struct{} {
+	return t.quit
+}
+
+// Returns a channel that is closed once quitting is requested a second time, to kill every process
+func (t *Tui) Kill() <-chan struct{} {
+	return t.kill
+}
+
+// Takes over the terminal, drawing the TUI and handling key presses until stopped
+func (t *Tui) Start() error {
+	restore, err := makeRawTerminal(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("the TUI requires a terminal: %w", err)
+	}
+	t.restore = restore
+	os.Stdout.WriteString(ansiEnterAltScreen)
+
+	// Redraw on status changes
+	for _, context := range t.contexts {
+		statusChannel := context.GetProcessNotificationChannel()
+		go func() {
+			for range statusChannel {
+				t.dirty.Store(true)
+			}
+		}()
+	}
+
+	go t.drawLoop(os.Stdout)
+	go t.readKeys(os.Stdin)
+	return nil
+}
+
+// Restores the terminal
+func (t *Tui) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.done)
+		os.Stdout.WriteString(ansiReset + ansiLeaveAltScreen)
+		if t.restore != nil {
+			t.restore()
+		}
+	})
+}
+
+// Draws the TUI whenever something changed
+func (t *Tui) drawLoop(out io.Writer) {
+	ticker := time.NewTicker(tuiFrameInterval)
+	defer ticker.Stop()
+	width, height := 0, 0
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+		w, h, err := terminalSize(os.Stdout)
+		if err != nil {
+			continue
+		}
+		if !t.dirty.Swap(false) && w == width && h == height {
+			continue
+		}
+		width, height = w, h
+		t.mutex.Lock()
+		frame := t.render(width, height)
+		t.mutex.Unlock()
+		io.WriteString(out, frame)
+	}
+}
+
+// Reads key presses until the TUI stops
+func (t *Tui) readKeys(in io.Reader) {
+	buffer := make([]byte, 256)
+	for {
+		n, err := in.Read(buffer)
+		if err != nil {
+			return
+		}
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+		for _, key := range parseKeys(buffer[:n]) {
+			t.handleKey(key)
+		}
+		t.dirty.Store(true)
+	}
+}
+
+// Splits terminal input into key names, printable characters are returned as they are
+func parseKeys(input []byte) []string {
+	sequences := map[string]string{
+		"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+		"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+		"\x1b[5~": "pgup", "\x1b[6~": "pgdown",
+		"\x1b[H": "home", "\x1b[F": "end", "\x1b[1~": "home", "\x1b[4~": "end",
+	}
+
+	keys := []string{}
+	text := string(input)
+	for len(text) > 0 {
+		if strings.HasPrefix(text, "\x1b") {
+			if len(text) == 1 {
+				keys = append(keys, "esc")
+				break
+			}
+			matched := false
+			for sequence, key := range sequences {
+				if strings.HasPrefix(text, sequence) {
+					keys = append(keys, key)
+					text = text[len(sequence):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// Skip unknown sequences
+				location := ansiSequence.FindStringIndex(text)
+				if location == nil || location[0] != 0 {
+					text = text[1:]
+				} else {
+					text = text[location[1]:]
+				}
+			}
+			continue
+		}
+
+		r := []rune(text)[0]
+		text = text[len(string(r)):]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, '\b':
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		default:
+			if r >= ' ' {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
+
+// Applies a key press
+func (t *Tui) handleKey(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.message = ""
+
+	if t.editingSearch {
+		switch key {
+		case "enter":
+			t.editingSearch = false
+		case "esc":
+			t.editingSearch = false
+			t.search[t.selected] = ""
+		case "backspace":
+			if runes := []rune(t.search[t.selected]); len(runes) > 0 {
+				t.search[t.selected] = string(runes[:len(runes)-1])
+			}
+		case "ctrl+c":
+			t.requestQuit()
+		default:
+			if len([]rune(key)) == 1 {
+				t.search[t.selected] += key
+			}
+		}
+		t.scroll[t.selected] = 0
+		return
+	}
+
+	views := len(t.contexts) + 1
+	switch key {
+	case "up", "k":
+		t.selected = (t.selected + views - 1) % views
+	case "down", "j", "tab":
+		t.selected = (t.selected + 1) % views
+	case "pgup":
+		t.scroll[t.selected] += 10
+	case "pgdown":
+		t.scroll[t.selected] = max(t.scroll[t.selected]-10, 0)
+	case "home":
+		t.scroll[t.selected] = t.logs[t.selected].len()
+	case "end":
+		t.scroll[t.selected] = 0
+	case "/":
+		t.editingSearch = true
+		t.search[t.selected] = ""
+	case "esc":
+		t.search[t.selected] = ""
+	case "r":
+		t.control("restart", (*ExecutionContext).RestartProcess)
+	case "s":
+		t.control("stop", (*ExecutionContext).StopProcess)
+	case "S":
+		t.control("start", (*ExecutionContext).StartProcess)
+	case "x":
+		t.control("kill", (*ExecutionContext).KillProcess)
+	case "t":
+		t.control("trigger", (*ExecutionContext).TriggerProcess)
+	case "q", "ctrl+c":
+		t.requestQuit()
+	}
+}
+
+// Runs the control command on the selected process, or every process in the combined view
+func (t *Tui) control(name string, command func(*ExecutionContext) error) {
+	contexts := t.contexts
+	if t.selected > 0 {
+		contexts = contexts[t.selected-1 : t.selected]
+	}
+	t.message = fmt.Sprintf("Sent %s", name)
+	// Commands wait for the process monitor, so they can't block drawing
+	go func() {
+		for _, context := range contexts {
+			if err := command(context); err != nil {
+				t.Message("Could not %s %s: %s", name, context.Process.Name, err.Error())
+			}
+		}
+	}()
+}
+
+// Closes the quit channel, and the kill channel when quitting again
+func (t *Tui) requestQuit() {
+	t.quitRequests++
+	switch t.quitRequests {
+	case 1:
+		t.message = "Shutting down all processes (press q again to kill them)"
+		close(t.quit)
+	case 2:
+		t.message = "Killing all processes"
+		close(t.kill)
+	}
+}
+
+// Returns the colour of the status
+func statusColour(status ProcessStatus) string {
+	switch status {
+	case ProcessStatusRunning, ProcessStatusHealthy:
+		return ansiGreen
+	case ProcessStatusFailed, ProcessStatusUnhealthy, ProcessStatusCrashLooping:
+		return ansiRed
+	case ProcessStatusWaitingTrigger, ProcessStatusWaitingDependencies, ProcessStatusRestarting, ProcessStatusStopped:
+		return ansiYellow
+	}
+	return ansiDim
+}
+
+// Returns the name shown for the process
+func (t *Tui) processName(index int) string {
+	process := t.contexts[index].Process
+	if process.Name != "" {
+		return process.Name
+	}
+	return process.Prefix
+}
+
+// Draws a full frame for the terminal size, the caller has to hold the mutex
+func (t *Tui) render(width int, height int) string {
+	if width < tuiSidebarWidth+10 || height < 3 {
+		return ansiHome + "Terminal too small" + ansiClearLine
+	}
+	paneWidth := width - tuiSidebarWidth - 1
+	rows := height - 1
+
+	// Process list, scrolled to keep the selected view visible when there are more views than rows
+	sidebar := []string{ansiBold + fitWidth(" Processes", tuiSidebarWidth) + ansiReset}
+	views := len(t.contexts) + 1
+	first := max(min(t.selected-(rows-1)/2, views-(rows-1)), 0)
+	for view := first; view < min(first+rows-1, views); view++ {
+		marker := "  "
+		if view == t.selected {
+			marker = "> "
+		}
+		if view == 0 {
+			sidebar = append(sidebar, fitWidth(marker+"All", tuiSidebarWidth))
+			continue
+		}
+		context := t.contexts[view-1]
+		status := context.GetStatusAsStr()
+		nameWidth := max(tuiSidebarWidth-len(marker)-len(status)-2, 4)
+		status = fitWidth(status, tuiSidebarWidth-len(marker)-nameWidth-2)
+		entry := marker + fitWidth(t.processName(view-1), nameWidth) + " " + statusColour(context.GetStatus()) + status + ansiReset + " "
+		if view == t.selected {
+			entry = ansiBold + entry
+		}
+		sidebar = append(sidebar, entry)
+	}
+
+	// Log pane title
+	title := "All processes"
+	if t.selected > 0 {
+		title = t.processName(t.selected-1) + " - " + t.contexts[t.selected-1].GetStatusAsStr()
+	}
+	search := t.search[t.selected]
+	if search != "" {
+		title += " - search: " + search
+	}
+
+	// Lines of the selected view that match the search
+	logs := t.logs[t.selected]
+	visible := []logLine{}
+	for i := 0; i < logs.len(); i++ {
+		if line := logs.get(i); t.matches(t.selected, line) {
+			visible = append(visible, line)
+		}
+	}
+	logRows := rows - 1
+	t.scroll[t.selected] = max(min(t.scroll[t.selected], len(visible)-logRows), 0)
+	if t.scroll[t.selected] > 0 {
+		title += fmt.Sprintf(" - scrolled up %d lines", t.scroll[t.selected])
+	}
+	end := len(visible) - t.scroll[t.selected]
+	visible = visible[max(end-logRows, 0):end]
+
+	pane := []string{ansiBold + fitWidth(" "+title, paneWidth) + ansiReset}
+	for _, line := range visible {
+		text := line.text
+		if t.selected == 0 {
+			text = t.processName(line.process) + " | " + text
+		}
+		text = strings.TrimRight(fitWidth(text, paneWidth), " ")
+		if search != "" {
+			// Highlight the first match
+			if start, end := indexFold(text, search); start >= 0 {
+				text = text[:start] + ansiReverse + text[start:end] + ansiReset + text[end:]
+			}
+		}
+		if line.isError {
+			text = ansiRed + strings.ReplaceAll(text, ansiReset, ansiReset+ansiRed) + ansiReset
+		}
+		pane = append(pane, text)
+	}
+
+	var frame strings.Builder
+	frame.WriteString(ansiHome)
+	for row := 0; row < rows; row++ {
+		left := strings.Repeat(" ", tuiSidebarWidth)
+		if row < len(sidebar) {
+			left = sidebar[row]
+		}
+		right := ""
+		if row < len(pane) {
+			right = pane[row]
+		}
+		frame.WriteString(left + ansiReset + ansiDim + "│" + ansiReset + right + ansiClearLine + "\r\n")
+	}
+
+	// Status bar
+	status := t.message
+	switch {
+	case t.editingSearch:
+		status = "Search: " + search + "_ (enter to keep, esc to clear)"
+	case status == "":
+		status = "up/down select  pgup/pgdown/home/end scroll  / search  r restart  s stop  S start  x kill  t trigger  q quit"
+	}
+	frame.WriteString(ansiReverse + fitWidth(" "+status, width) + ansiReset)
+	return frame.String()
+}
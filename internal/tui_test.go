@@ -0,0 +1,251 @@
+package pp
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Removes the escape sequences of a rendered frame and splits it into rows
+func frameRows(frame string) []string {
+	frame = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`).ReplaceAllString(frame, "")
+	return strings.Split(frame, "\r\n")
+}
+
+// Creates a TUI for processes with the names
+func createTestTui(names ...string) *Tui {
+	var wg sync.WaitGroup
+	contexts := []*ExecutionContext{}
+	for _, name := range names {
+		process := Process{Name: name, Prefix: name}
+		contexts = append(contexts, process.CreateContext(&wg))
+	}
+	return CreateTui(contexts)
+}
+
+// Ensure that the log buffer keeps the most recent lines in order
+func TestLogBuffer(t *testing.T) {
+	t.Parallel()
+
+	buffer := newLogBuffer(3)
+	for _, text := range []string{"1", "2", "3", "4", "5"} {
+		buffer.append(logLine{text: text})
+	}
+	assert.Equal(t, 3, buffer.len())
+	lines := []string{}
+	for i := 0; i < buffer.len(); i++ {
+		lines = append(lines, buffer.get(i).text)
+	}
+	assert.Equal(t, []string{"3", "4", "5"}, lines)
+}
+
+// Ensure that terminal input is split into keys
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		keys  []string
+	}{
+		{"characters", "rq", []string{"r", "q"}},
+		{"arrows", "\x1b[A\x1b[B\x1bOA", []string{"up", "down", "up"}},
+		{"paging", "\x1b[5~\x1b[6~\x1b[H\x1b[4~", []string{"pgup", "pgdown", "home", "end"}},
+		{"escape", "\x1b", []string{"esc"}},
+		{"control keys", "\r\t\x7f\x03", []string{"enter", "tab", "backspace", "ctrl+c"}},
+		{"unknown sequence", "\x1b[15~x", []string{"x"}},
+		{"unicode", "é", []string{"é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.keys, parseKeys([]byte(tt.input)))
+		})
+	}
+}
+
+// Ensure that escape sequences and control characters are removed from output
+func TestCleanLine(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "ready in 10 ms", cleanLine("\x1b[32mready\x1b[0m in \x1b[1m10\x1b[22m ms"))
+	assert.Equal(t, "    indented", cleanLine("\tindented\r"))
+	assert.Equal(t, "title", cleanLine("\x1b]0;window\x07title"))
+}
+
+// Ensure that output is split per process and combined in the first view
+func TestTuiViews(t *testing.T) {
+	t.Parallel()
+
+	tui := createTestTui("api", "web")
+	tui.contexts[0].infoWriter.Write([]byte("listening on :8080\n"))
+	tui.contexts[1].errorWriter.Write([]byte("compile error\n"))
+	tui.contexts[0].infoWriter.Write([]byte("GET /\nGET /health\n"))
+
+	rows := frameRows(tui.render(100, 10))
+	assert.Len(t, rows, 10)
+	assert.Contains(t, rows[0], "All processes")
+	assert.Contains(t, rows[1], "> All")
+	assert.Contains(t, rows[2], "api")
+	assert.Contains(t, rows[3], "web")
+	assert.Contains(t, rows[1], "api | listening on :8080")
+	assert.Contains(t, rows[2], "web | compile error")
+	assert.Contains(t, rows[4], "api | GET /health")
+
+	tui.handleKey("down")
+	rows = frameRows(tui.render(100, 10))
+	assert.Contains(t, rows[0], "api - Not started")
+	assert.Contains(t, rows[2], "> api")
+	assert.Contains(t, rows[1], "│listening on :8080")
+	assert.Contains(t, rows[3], "│GET /health")
+	assert.NotContains(t, strings.Join(rows, "\n"), "compile error")
+
+	tui.handleKey("up")
+	tui.handleKey("up")
+	assert.Equal(t, 2, tui.selected, "Selection should wrap around")
+}
+
+// Ensure that searching filters the lines and scrolling stays within the output
+func TestTuiSearchAndScroll(t *testing.T) {
+	t.Parallel()
+
+	tui := createTestTui("api")
+	for _, line := range []string{"GET /", "POST /login", "GET /health", "POST /logout"} {
+		tui.contexts[0].infoWriter.Write([]byte(line + "\n"))
+	}
+
+	for _, key := range parseKeys([]byte("/post\r")) {
+		tui.handleKey(key)
+	}
+	rows := frameRows(tui.render(100, 10))
+	assert.Contains(t, rows[0], "search: post")
+	assert.Contains(t, rows[1], "POST /login")
+	assert.Contains(t, rows[2], "POST /logout")
+	assert.NotContains(t, strings.Join(rows, "\n"), "GET")
+
+	tui.handleKey("esc")
+	rows = frameRows(tui.render(100, 4))
+	assert.Contains(t, rows[1], "api | GET /health")
+	assert.Contains(t, rows[2], "api | POST /logout")
+
+	tui.handleKey("pgup")
+	rows = frameRows(tui.render(100, 4))
+	assert.Contains(t, rows[0], "scrolled up 2 lines", "Scrolling should stop at the first line")
+	assert.Contains(t, rows[1], "api | GET /")
+	assert.Contains(t, rows[2], "api | POST /login")
+
+	tui.contexts[0].infoWriter.Write([]byte("GET /new\n"))
+	rows = frameRows(tui.render(100, 4))
+	assert.Contains(t, rows[1], "api | GET /", "Scrolled views should not move on new output")
+
+	tui.handleKey("end")
+	rows = frameRows(tui.render(100, 4))
+	assert.Contains(t, rows[2], "api | GET /new")
+}
+
+// Ensure that matches are found without lowercasing the text, which can change its length
+func TestIndexFold(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		search string
+		match  string
+	}{
+		{"GET /health", "health", "health"},
+		{"POST /Login", "login", "Login"},
+		{"İİ ERROR: disk full", "error", "ERROR"},
+		{"Größe überschritten", "ÜBER", "über"},
+		{"no match", "error", ""},
+		{"err", "error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			start, end := indexFold(tt.text, tt.search)
+			if tt.match == "" {
+				assert.Equal(t, -1, start)
+				return
+			}
+			assert.Equal(t, tt.match, tt.text[start:end])
+		})
+	}
+
+	tui := createTestTui("api")
+	tui.contexts[0].infoWriter.Write([]byte("İİ ERROR: disk full\n"))
+	for _, key := range parseKeys([]byte("/error\r")) {
+		tui.handleKey(key)
+	}
+	assert.Contains(t, tui.render(100, 10), ansiReverse+"ERROR"+ansiReset, "The match should be highlighted")
+}
+
+// Ensure that every view keeps its own search
+func TestTuiSearchPerView(t *testing.T) {
+	t.Parallel()
+
+	tui := createTestTui("api", "web")
+	tui.contexts[0].infoWriter.Write([]byte("GET /\nPOST /login\n"))
+	tui.contexts[1].infoWriter.Write([]byte("compiled\nreloaded\n"))
+
+	tui.handleKey("down")
+	for _, key := range parseKeys([]byte("/post\r")) {
+		tui.handleKey(key)
+	}
+	tui.handleKey("down")
+	rows := frameRows(tui.render(100, 10))
+	assert.NotContains(t, rows[0], "search", "The search should not apply to other views")
+	assert.Contains(t, rows[1], "compiled")
+	assert.Contains(t, rows[2], "reloaded")
+
+	tui.handleKey("up")
+	rows = frameRows(tui.render(100, 10))
+	assert.Contains(t, rows[0], "search: post")
+	assert.Contains(t, rows[1], "POST /login")
+	assert.NotContains(t, strings.Join(rows, "\n"), "GET")
+}
+
+// Ensure that the process list scrolls to keep the selected view visible
+func TestTuiSidebarScroll(t *testing.T) {
+	t.Parallel()
+
+	tui := createTestTui("p1", "p2", "p3", "p4", "p5", "p6")
+	rows := frameRows(tui.render(100, 5))
+	assert.Contains(t, rows[1], "> All")
+	assert.Contains(t, rows[3], "p2")
+
+	for range 5 {
+		tui.handleKey("down")
+	}
+	rows = frameRows(tui.render(100, 5))
+	assert.Contains(t, rows[1], "p4")
+	assert.Contains(t, rows[2], "> p5")
+	assert.Contains(t, rows[3], "p6")
+
+	tui.handleKey("down")
+	rows = frameRows(tui.render(100, 5))
+	assert.Contains(t, rows[3], "> p6", "The last view should stay at the bottom of the list")
+	assert.NotContains(t, strings.Join(rows, "\n"), "All")
+}
+
+// Ensure that quitting twice closes the quit and kill channels
+func TestTuiQuit(t *testing.T) {
+	t.Parallel()
+
+	tui := createTestTui("api")
+	tui.handleKey("q")
+	select {
+	case <-tui.Quit():
+	default:
+		t.Fatal("Quit should be closed")
+	}
+	select {
+	case <-tui.Kill():
+		t.Fatal("Kill should not be closed after quitting once")
+	default:
+	}
+	tui.handleKey("ctrl+c")
+	<-tui.Kill()
+	tui.handleKey("q")
+}
@@ -1,46 +1,124 @@
 package pp
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"golang.org/x/sys/windows"
 )
 
+// Signals are limited on windows, only the common signals are supported
+var platformSignals = map[string]syscall.Signal{}
+
+// Highest signal number accepted by stop_signal
+const maxSignal = 15
+
+// Starts the command in its own process group so it can be sent CTRL_BREAK_EVENT without stopping process party
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// Immediately kills the process and all of its children
+func killProcessGroup(pid int) error {
+	return exec.Command("TASKKILL", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// Windows has no stop signals, SIGKILL kills the process and all of its children and every other
+// signal sends CTRL_BREAK_EVENT to the process group
+func signalProcessGroup(pid int, sig os.Signal) error {
+	if sig == syscall.SIGKILL {
+		return killProcessGroup(pid)
+	}
+	if err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid)); err != nil {
+		return fmt.Errorf("could not send CTRL_BREAK_EVENT: %w", err)
+	}
+	return nil
+}
+
+// Child processes are killed with their parent by TASKKILL, so there is nothing left to clean up
+func (c *ExecutionContext) cleanupProcessGroup(pid int, processDone chan struct{}) error {
+	return nil
+}
+
+// Stops the running command with CTRL_BREAK_EVENT, killing the process tree if it does not exit within the stop timeout.
+// A stop_signal of SIGKILL kills the tree immediately
 func (c *ExecutionContext) killExecution() error {
 	c.internalExit.Store(true)
-	c.executionMutex.Lock()
-	defer c.executionMutex.Unlock()
+	c.executionMutex.RLock()
+	cmd := c.cmd
+	processDone := c.processDone
+	c.executionMutex.RUnlock()
 
-	if c.cmd == nil {
+	if cmd == nil || cmd.Process == nil || processDone == nil {
 		return nil
 	}
 
-	if c.cmd.Process == nil {
+	// Already exited
+	select {
+	case <-processDone:
 		return nil
+	default:
 	}
 
-	if c.cmd.ProcessState != nil {
-		return nil
+	stopSignal, err := c.Process.GetStopSignal()
+	if err != nil {
+		return err
 	}
+	stopTimeout := c.Process.GetStopTimeout()
+	pid := cmd.Process.Pid
 
-	if c.Process.Pid == "" {
-		return nil
+	if stopSignal != syscall.SIGKILL {
+		c.infoWriter.Printf("Stopping process - %d (CTRL_BREAK_EVENT, %s timeout)", pid, stopTimeout)
+		if err := signalProcessGroup(pid, stopSignal); err == nil {
+			select {
+			case <-processDone:
+				return nil
+			case <-time.After(stopTimeout):
+				c.errorWriter.Printf("Process did not stop within %s, killing it", stopTimeout)
+			}
+		} else {
+			c.errorWriter.Printf("%s, killing the process", err.Error())
+		}
 	}
 
-	pid := c.Process.Pid
-
-	c.infoWriter.Printf("Killing process - %s", pid)
-	// On Windows, we need to kill the entire process group to ensure child processes are terminated
+	c.infoWriter.Printf("Killing process - %d", pid)
+	// Kill the entire process tree to ensure child processes are terminated
 	// https://github.com/air-verse/air/blob/master/runner/util_windows.go
-	kill := exec.Command("TASKKILL", "/T", "/F", "/PID", pid)
-	if err := kill.Run(); err != nil {
-		// Wait 100 miliseconds and try again
-		time.Sleep(time.Duration(100 * time.Millisecond))
-		kill := exec.Command("TASKKILL", "/T", "/F", "/PID", pid)
-		if err := kill.Run(); err != nil {
-			c.cmd.Process.Kill()
-			return err
+	if err := killProcessGroup(pid); err != nil {
+		if err := cmd.Process.Kill(); err != nil {
+			select {
+			case <-processDone:
+				return nil
+			default:
+				return err
+			}
 		}
 	}
-	c.cmd.Process.Kill()
+	<-processDone
 	return nil
 }
+
+// Returns the users shell and the flag used to pass it a command string
+func defaultShell() (string, string) {
+	if shell := os.Getenv("COMSPEC"); shell != "" {
+		return shell, "/C"
+	}
+	return "cmd.exe", "/C"
+}
+
+// Joins arguments so cmd.exe reads them back as the same arguments
+func joinShellArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"&|<>^") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
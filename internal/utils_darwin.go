@@ -1,47 +1,130 @@
 package pp
 
 import (
-	"strconv"
+	"fmt"
+	"os"
+	"os/exec"
 	"syscall"
 	"time"
 )
 
-func (c *ExecutionContext) killExecution() error {
-	c.internalExit.Store(true)
-	c.executionMutex.Lock()
-	defer c.executionMutex.Unlock()
+// Signals only available on unix systems
+var platformSignals = map[string]syscall.Signal{
+	"SIGUSR1":  syscall.SIGUSR1,
+	"SIGUSR2":  syscall.SIGUSR2,
+	"SIGWINCH": syscall.SIGWINCH,
+	"SIGCONT":  syscall.SIGCONT,
+	"SIGSTOP":  syscall.SIGSTOP,
+	"SIGTSTP":  syscall.SIGTSTP,
+}
 
-	if c.cmd == nil {
-		return nil
-	}
+// Highest signal number accepted by stop_signal
+const maxSignal = 31
 
-	if c.cmd.Process == nil {
-		return nil
-	}
+// Starts the command in its own process group so the whole process tree can be signalled
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
 
-	if c.cmd.ProcessState != nil {
-		return nil
+// Sends a signal to every process in the process group
+func signalProcessGroup(pgid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %s", sig)
 	}
+	return syscall.Kill(-pgid, s)
+}
 
-	if c.Process.Pid == "" {
-		return nil
+// Returns true if any process in the process group is still alive
+func processGroupAlive(pgid int) bool {
+	return syscall.Kill(-pgid, 0) == nil
+}
+
+// Immediately kills every process in the process group
+func killProcessGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+// Waits for the process to exit and for every process in its group to end, returns false if the deadline is reached first
+func waitForProcessGroup(pgid int, processDone chan struct{}, deadline time.Time) bool {
+	select {
+	case <-processDone:
+	case <-time.After(time.Until(deadline)):
+		return false
 	}
+	for processGroupAlive(pgid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return true
+}
 
-	pid, err := strconv.Atoi(c.Process.Pid)
+// Sends the stop signal to the process group, killing the group if any process survives the stop timeout
+func (c *ExecutionContext) stopProcessGroup(pgid int, processDone chan struct{}) error {
+	stopSignal, err := c.Process.GetStopSignal()
 	if err != nil {
 		return err
 	}
+	stopTimeout := c.Process.GetStopTimeout()
 
-	c.infoWriter.Printf("Killing process - %d", pid)
-	// Send SIGINT signal first
-	if err := syscall.Kill(pid, syscall.SIGINT); err != nil {
-		// Wait 100 milliseconds and try again
-		time.Sleep(time.Duration(100 * time.Millisecond))
-		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
-			c.cmd.Process.Kill()
-			return err
+	c.infoWriter.Printf("Stopping process group - %d (%s, %s timeout)", pgid, stopSignal, stopTimeout)
+	if err := signalProcessGroup(pgid, stopSignal); err == nil {
+		if waitForProcessGroup(pgid, processDone, time.Now().Add(stopTimeout)) {
+			return nil
 		}
+		c.errorWriter.Printf("Process group did not stop within %s, killing it", stopTimeout)
+	}
+
+	killProcessGroup(pgid)
+	// Verify that no descendants survived
+	if !waitForProcessGroup(pgid, processDone, time.Now().Add(time.Second)) {
+		return fmt.Errorf("processes in group %d survived SIGKILL", pgid)
 	}
-	c.cmd.Process.Kill()
 	return nil
 }
+
+// Stops any processes left in the process group after the process exited
+func (c *ExecutionContext) cleanupProcessGroup(pgid int, processDone chan struct{}) error {
+	if !processGroupAlive(pgid) {
+		return nil
+	}
+	c.infoWriter.Printf("Process exited with child processes still running")
+	return c.stopProcessGroup(pgid, processDone)
+}
+
+// Gracefully stops the running command and its children with the stop signal, killing them if they do not exit within the stop timeout
+func (c *ExecutionContext) killExecution() error {
+	c.internalExit.Store(true)
+	c.executionMutex.RLock()
+	cmd := c.cmd
+	processDone := c.processDone
+	c.executionMutex.RUnlock()
+
+	if cmd == nil || cmd.Process == nil || processDone == nil {
+		return nil
+	}
+
+	// Already exited
+	select {
+	case <-processDone:
+		return nil
+	default:
+	}
+
+	return c.stopProcessGroup(cmd.Process.Pid, processDone)
+}
+
+// Returns the users shell and the flag used to pass it a command string
+func defaultShell() (string, string) {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell, "-c"
+	}
+	return "/bin/sh", "-c"
+}
+
+// Joins arguments so the shell reads them back as the same arguments
+func joinShellArgs(args []string) string {
+	return joinCommand(args)
+}
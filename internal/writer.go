@@ -1,19 +1,66 @@
 package pp
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	color "github.com/fatih/color"
 )
 
+// Lifecycle events written as typed events by the JSON output
+const (
+	EventStarted    = "started"
+	EventExited     = "exited"
+	EventTriggered  = "triggered"
+	EventRestarting = "restarting"
+)
+
+// Time a partial line is kept before it is written without its newline, e.g. for prompts
+const LineFlushTimeout = 100 * time.Millisecond
+
+// Line of the JSON output, either a line of output or a message from process party
+type OutputLine struct {
+	Type     string       `json:"type"` // log or event
+	Time     time.Time    `json:"time"`
+	Process  string       `json:"process"`
+	Prefix   string       `json:"prefix"`
+	Pid      int          `json:"pid,omitempty"`
+	Stream   string       `json:"stream"`   // stdout, stderr or system for messages from process party
+	Severity string       `json:"severity"` // info or error
+	Message  string       `json:"message"`
+	Event    string       `json:"event,omitempty"`     // Lifecycle event of events, e.g. started
+	ExitCode *int         `json:"exit_code,omitempty"` // Exit code of exited events
+	Trigger  string       `json:"trigger,omitempty"`   // Kind of trigger of triggered events (fs, process or manual)
+	Changes  []FileChange `json:"changes,omitempty"`   // Changed files of triggered events of fs triggers
+}
+
 type customWriter struct {
-	w        io.Writer
-	severity string
-	process  *Process
-	prefix   string
+	w         io.Writer
+	severity  string
+	process   *Process
+	prefix    string
+	noPrefix  bool           // Set when the output is not shared with other processes
+	history   *logHistory    // Keeps the recent lines of the process (optional)
+	lines     *atomic.Int64  // Counts the lines written by the process (optional)
+	file      *logFileWriter // Tees the output to the log file of the process (optional)
+	json      bool           // Writes every line as a JSON object
+	pid       *atomic.Int64  // PID of the process written by the JSON output (optional)
+	assembler *lineAssembler // Keeps partial lines written by the process until they are complete (optional)
+}
+
+// Assembles the output of a stream into lines, as the output can be split anywhere between writes
+type lineAssembler struct {
+	mutex      sync.Mutex
+	partial    []byte
+	timer      *time.Timer
+	generation int  // Incremented when the partial line changes, so a timer only flushes the partial line it was started for
+	flushed    bool // Set when a partial line was flushed, the newline completing it is not written as a blank line
 }
 
 // Returns true if the line is empty
@@ -30,35 +77,151 @@ func emptyMessage(s string) bool {
 
 // Creates the prefix so we dont need to do it on every message
 func (c *customWriter) createPrefix() {
-	if c.process.Prefix == "" && !c.process.DisplayPid {
-		c.prefix = ""
+	c.prefix = c.plainPrefix()
+	if c.prefix == "" {
 		return
 	}
-	c.prefix = "[" + c.process.Prefix
+	colourFunc := c.process.GetFgColour()
+	c.prefix = colourFunc(c.prefix)
+	c.prefix += " "
+}
+
+// Returns the prefix without colours or spacing, empty if the process has none
+func (c customWriter) plainPrefix() string {
+	if c.process.Prefix == "" && !c.process.DisplayPid {
+		return ""
+	}
+	prefix := "[" + c.process.Prefix
 	if c.process.DisplayPid {
 		if c.process.Pid == "" {
-			c.prefix = c.prefix + "-     "
+			prefix = prefix + "-     "
 		} else {
-			c.prefix = c.prefix + "-" + c.process.Pid
+			prefix = prefix + "-" + c.process.Pid
 		}
 	}
-	c.prefix = c.prefix + "]"
-	colourFunc := c.process.GetFgColour()
-	c.prefix = colourFunc(c.prefix)
-	c.prefix += " "
+	return prefix + "]"
+}
+
+// Colours the text if the terminal supports colours
+func terminalColour(attribute color.Attribute, text string) string {
+	return color.New(attribute).Sprint(text)
 }
 
 // Utility function to simplyfy printing strings
 func (c customWriter) Printf(format string, a ...any) {
-	c.Write([]byte(color.HiBlackString(fmt.Sprintf(format, a...))))
+	c.write([]byte(fmt.Sprintf(format, a...)), false, nil)
+}
+
+// Prints a lifecycle event, written as a typed event by the JSON output and as a message otherwise
+func (c customWriter) Eventf(event OutputLine, format string, a ...any) {
+	c.write([]byte(fmt.Sprintf(format, a...)), false, &event)
 }
 
+// Writes the complete lines written by the process, partial lines are kept until they are completed or flushed
 func (c customWriter) Write(p []byte) (int, error) {
-	if c.process.Silent {
-		return 0, nil
+	if c.assembler == nil {
+		return c.write(p, true, nil)
 	}
+	c.assembler.mutex.Lock()
+	defer c.assembler.mutex.Unlock()
+	if c.process.Silent && c.file == nil {
+		return len(p), nil
+	}
+	lines := c.assembler.add(p, c.flushTimedOut)
+	if len(lines) != 0 {
+		if _, err := c.write(lines, true, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Writes the partial line of the process, e.g. once the process exited
+func (c customWriter) flush() {
+	if c.assembler == nil {
+		return
+	}
+	c.assembler.mutex.Lock()
+	defer c.assembler.mutex.Unlock()
+	c.writePartial()
+}
+
+// Writes the partial line once it was kept for LineFlushTimeout, unless it changed since the timer started
+func (c customWriter) flushTimedOut(generation int) {
+	c.assembler.mutex.Lock()
+	defer c.assembler.mutex.Unlock()
+	if c.assembler.generation == generation {
+		c.writePartial()
+		c.assembler.flushed = true
+	}
+}
 
-	if c.prefix == "" && (c.process.Prefix != "" || c.process.DisplayPid) {
+// Writes the partial line as a complete line. Has to be called with the mutex locked
+func (c customWriter) writePartial() {
+	if partial := c.assembler.take(); len(partial) != 0 {
+		c.write(append(partial, '\n'), true, nil)
+	}
+}
+
+// Adds the output to the partial line and returns the lines it completed. The remaining partial line is flushed
+// after LineFlushTimeout if it is not completed. Has to be called with the mutex locked
+func (a *lineAssembler) add(p []byte, flush func(generation int)) []byte {
+	if a.flushed && len(p) != 0 {
+		p = bytes.TrimPrefix(bytes.TrimPrefix(p, []byte("\r")), []byte("\n"))
+		a.flushed = false
+	}
+	a.partial = append(a.partial, p...)
+	end := bytes.LastIndexByte(a.partial, '\n')
+	if end < 0 {
+		// Keep waiting for the partial line that is already waiting
+		if a.timer == nil {
+			a.startTimer(flush)
+		}
+		return nil
+	}
+	lines := a.partial[:end+1]
+	a.partial = append([]byte{}, a.partial[end+1:]...)
+	a.stopTimer()
+	if len(a.partial) != 0 {
+		a.startTimer(flush)
+	}
+	return lines
+}
+
+// Returns the partial line and clears it. Has to be called with the mutex locked
+func (a *lineAssembler) take() []byte {
+	partial := a.partial
+	a.partial = nil
+	a.stopTimer()
+	return partial
+}
+
+func (a *lineAssembler) startTimer(flush func(generation int)) {
+	generation := a.generation
+	a.timer = time.AfterFunc(LineFlushTimeout, func() {
+		flush(generation)
+	})
+}
+
+func (a *lineAssembler) stopTimer() {
+	a.generation++
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+}
+
+// Writes the lines with the prefix, lines that were not written by the process are shown as messages and not counted
+func (c customWriter) write(p []byte, fromProcess bool, event *OutputLine) (int, error) {
+	// Silent processes only write to their log file. The output is reported as written, a short write breaks
+	// the pipe of the command and kills the process
+	if c.process.Silent && c.file == nil || len(p) == 0 {
+		return len(p), nil
+	}
+
+	if c.noPrefix {
+		c.prefix = ""
+	} else if c.prefix == "" && (c.process.Prefix != "" || c.process.DisplayPid) {
 		c.createPrefix()
 	}
 
@@ -75,18 +238,39 @@ func (c customWriter) Write(p []byte) (int, error) {
 	// Take out common line seperation
 	message = strings.Replace(message, "\r", "", -1)
 	x := strings.Split(message, "\n")
+	// A trailing newline ends the last line
+	if len(x) > 1 && x[len(x)-1] == "" {
+		x = x[:len(x)-1]
+	}
 
 	for _, message := range x {
-		if emptyMessage(message) {
-			break
+		// Blank lines written by the process are kept
+		if !fromProcess && emptyMessage(message) {
+			continue
+		}
+		if c.history != nil {
+			c.history.add(message, c.severity == "error")
+		}
+		if fromProcess && c.lines != nil {
+			c.lines.Add(1)
 		}
-		if c.severity == "error" {
-			message = color.RedString(message)
+		if c.file != nil {
+			prefix := ""
+			if c.file.options.Prefix {
+				if prefix = c.plainPrefix(); prefix != "" {
+					prefix = c.file.colour(c.process.getFgAttribute(), prefix) + " "
+				}
+			}
+			c.file.writeLine(c.formatLine(c.file.clean(message), fromProcess, timeString, prefix, c.file.colour))
 		}
-		if c.process.ShowTimestamp {
-			message = timeString + "	" + message
+		if c.process.Silent {
+			continue
 		}
-		n, err := c.w.Write([]byte(c.prefix + message + "\n"))
+		line := c.formatLine(message, fromProcess, timeString, c.prefix, terminalColour)
+		if c.json {
+			line = c.jsonLine(message, fromProcess, now, event)
+		}
+		n, err := c.w.Write([]byte(line))
 		if err != nil {
 			return n, err
 		}
@@ -95,3 +279,47 @@ func (c customWriter) Write(p []byte) (int, error) {
 
 	return len(p), nil
 }
+
+// Formats a line of output, messages from process party and errors are coloured by the colour function
+func (c customWriter) formatLine(message string, fromProcess bool, timeString string, prefix string, colour func(color.Attribute, string) string) string {
+	if !fromProcess {
+		message = colour(color.FgHiBlack, message)
+	}
+	if c.severity == "error" {
+		message = colour(color.FgRed, message)
+	}
+	if c.process.ShowTimestamp {
+		message = timeString + "	" + message
+	}
+	return prefix + message + "\n"
+}
+
+// Formats a line of output as a JSON object, messages from process party are written to the system stream
+func (c customWriter) jsonLine(message string, fromProcess bool, now time.Time, event *OutputLine) string {
+	line := OutputLine{Type: "log"}
+	if event != nil {
+		line = *event
+		line.Type = "event"
+	}
+	line.Time = now
+	line.Process = c.process.displayName()
+	line.Prefix = c.process.Prefix
+	if c.pid != nil {
+		line.Pid = int(c.pid.Load())
+	}
+	line.Severity = c.severity
+	line.Message = ansiSequence.ReplaceAllString(message, "")
+	switch {
+	case !fromProcess:
+		line.Stream = "system"
+	case c.severity == "error":
+		line.Stream = "stderr"
+	default:
+		line.Stream = "stdout"
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return ""
+	}
+	return string(encoded) + "\n"
+}
@@ -1,9 +1,13 @@
 package pp
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -169,12 +173,12 @@ func TestLineSeparation(t *testing.T) {
 			expectedLines: 3,
 			containsEmpty: false,
 		},
-		// {
-		// 	name:          "empty lines filtered",
-		// 	input:         "line1\n\nline2\n\nline3",
-		// 	expectedLines: 3,
-		// 	containsEmpty: false,
-		// },
+		{
+			name:          "blank lines preserved",
+			input:         "line1\n\nline2\n  \nline3",
+			expectedLines: 5,
+			containsEmpty: true,
+		},
 		{
 			name:          "single line preserved",
 			input:         "single line\n",
@@ -251,7 +255,7 @@ func TestSilentMode(t *testing.T) {
 
 	n, err := writer.Write([]byte("test message"))
 	assert.NoError(t, err)
-	assert.Equal(t, 0, n)
+	assert.Equal(t, len("test message"), n, "Dropped output should be reported as written so the pipe is not broken")
 	assert.Empty(t, mock.written)
 }
 
@@ -315,3 +319,135 @@ func TestErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+// Ensure that the JSON output writes every line as an object and events as typed events
+func TestCustomWriterJson(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockWriter{}
+	process := &Process{Prefix: "TEST"}
+	pid := &atomic.Int64{}
+	pid.Store(42)
+	writer := &customWriter{w: mock, process: process, severity: "error", json: true, pid: pid}
+
+	n, err := writer.Write([]byte("\x1b[31mfirst\x1b[0m\nsecond\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 22, n)
+	code := 3
+	writer.Eventf(OutputLine{Event: EventExited, ExitCode: &code}, "Exited with %d", code)
+
+	lines := strings.Split(strings.TrimSuffix(string(mock.written), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	decoded := []OutputLine{}
+	for _, line := range lines {
+		output := OutputLine{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &output))
+		decoded = append(decoded, output)
+	}
+
+	assert.Equal(t, "log", decoded[0].Type)
+	assert.Equal(t, "TEST", decoded[0].Process)
+	assert.Equal(t, 42, decoded[0].Pid)
+	assert.Equal(t, "stderr", decoded[0].Stream)
+	assert.Equal(t, "error", decoded[0].Severity)
+	assert.Equal(t, "first", decoded[0].Message)
+	assert.Equal(t, "second", decoded[1].Message)
+	assert.False(t, decoded[0].Time.IsZero())
+
+	assert.Equal(t, "event", decoded[2].Type)
+	assert.Equal(t, EventExited, decoded[2].Event)
+	assert.Equal(t, "system", decoded[2].Stream)
+	assert.Equal(t, "Exited with 3", decoded[2].Message)
+	assert.Equal(t, 3, *decoded[2].ExitCode)
+	assert.NotContains(t, lines[0], "exit_code")
+}
+
+// Ensure that partial lines are written once they are complete, or after the flush timeout
+func TestLineAssembler(t *testing.T) {
+	t.Parallel()
+
+	mock := &lockedMockWriter{}
+	process := &Process{Prefix: "TEST"}
+	writer := &customWriter{w: mock, process: process, severity: "info", assembler: &lineAssembler{}}
+
+	n, err := writer.Write([]byte("hel"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Empty(t, mock.String())
+
+	writer.Write([]byte("lo\n\nwor"))
+	assert.Equal(t, "[TEST] hello\n[TEST] \n", mock.String())
+	writer.Write([]byte("ld\n"))
+	assert.Equal(t, "[TEST] hello\n[TEST] \n[TEST] world\n", mock.String())
+
+	// Prompts without a newline are written after the timeout
+	writer.Write([]byte("prompt> "))
+	assert.NotContains(t, mock.String(), "prompt")
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(mock.String(), "[TEST] prompt> \n")
+	}, 10*LineFlushTimeout, LineFlushTimeout/10)
+	// The newline completing the prompt is not written as a blank line
+	writer.Write([]byte("\nanswer\n"))
+	assert.True(t, strings.HasSuffix(mock.String(), "[TEST] prompt> \n[TEST] answer\n"))
+
+	// Flushing writes the partial line immediately and only once
+	writer.Write([]byte("exiting"))
+	writer.flush()
+	assert.True(t, strings.HasSuffix(mock.String(), "[TEST] exiting\n"))
+	time.Sleep(2 * LineFlushTimeout)
+	assert.Equal(t, 1, strings.Count(mock.String(), "exiting"))
+}
+
+// Ensure that the output of processes written in chunks split mid-line is not mixed between lines
+func TestInterleavedOutput(t *testing.T) {
+	t.Parallel()
+
+	mock := &lockedMockWriter{}
+	lineCount := 200
+	var wg sync.WaitGroup
+	for _, prefix := range []string{"A", "B", "C"} {
+		writer := &customWriter{w: mock, process: &Process{Prefix: prefix}, severity: "info", assembler: &lineAssembler{}}
+		output := ""
+		for i := range lineCount {
+			output += fmt.Sprintf("%s line %d\n", prefix, i)
+		}
+		wg.Add(1)
+		// Write the output in chunks that do not line up with the lines
+		go func() {
+			defer wg.Done()
+			for len(output) > 0 {
+				size := min(7, len(output))
+				writer.Write([]byte(output[:size]))
+				output = output[size:]
+			}
+		}()
+	}
+	wg.Wait()
+
+	next := map[string]int{}
+	lines := strings.Split(strings.TrimSuffix(mock.String(), "\n"), "\n")
+	assert.Len(t, lines, 3*lineCount)
+	for _, line := range lines {
+		prefix := line[1:2]
+		assert.Equal(t, fmt.Sprintf("[%s] %s line %d", prefix, prefix, next[prefix]), line)
+		next[prefix]++
+	}
+}
+
+// mockWriter that can be written to by multiple writers
+type lockedMockWriter struct {
+	mutex sync.Mutex
+	mockWriter
+}
+
+func (m *lockedMockWriter) Write(p []byte) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.mockWriter.Write(p)
+}
+
+func (m *lockedMockWriter) String() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return string(m.written)
+}
@@ -71,6 +71,76 @@ func CreateFailCmdSettings() CmdSettings {
 	}
 }
 
+// Create a command that exits with the exit code
+func CreateExitCmdSettings(exitCode int) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"exit", fmt.Sprintf("%d", exitCode)},
+	}
+}
+
+// Create a command that ignores stop signals while sleeping
+func CreateIgnoreSignalsCmdSettings(sleepDurationSeconds int) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"ignore-signals", fmt.Sprintf("%d", sleepDurationSeconds)},
+	}
+}
+
+// Create a command that only exits early on SIGTERM
+func CreateTrapCmdSettings(sleepDurationSeconds int) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"trap", fmt.Sprintf("%d", sleepDurationSeconds)},
+	}
+}
+
+// Create a command that echoes its standard input and exits once it is closed
+func CreateStdinCmdSettings(timeoutSeconds int) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"stdin", fmt.Sprintf("%d", timeoutSeconds)},
+	}
+}
+
+// Create a command that spawns a long running child process, writing the childs PID to a file
+func CreateSpawnCmdSettings(pidFile string, sleepDurationSeconds int) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"spawn", pidFile, fmt.Sprintf("%d", sleepDurationSeconds)},
+	}
+}
+
 // Run the custom touch command
 func Touch(path string) error {
 	x := CreateTouchCmdSettings(path)
@@ -86,3 +156,31 @@ func Mkdir(path string) error {
 	_, err := cmd.Output()
 	return err
 }
+
+// Create a command that writes lines in chunks split mid-line and a prompt without a newline
+func CreatePartialCmdSettings() CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: []string{"partial"},
+	}
+}
+
+// Create a command that prints the given environment variables
+func CreateEnvCmdSettings(names ...string) CmdSettings {
+	currentOS := runtime.GOOS
+	local := command
+
+	if currentOS == "windows" {
+		local += ".exe"
+	}
+	return CmdSettings{
+		Cmd:  local,
+		Args: append([]string{"env"}, names...),
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that a running party can be inspected and controlled through the HTTP API
+func TestApi(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateStdinCmdSettings(10)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.Name = "repl"
+	// Silent processes do not keep their output
+	process.Silent = false
+	context := process.CreateContext(&wg)
+	context.SetOutput(io.Discard, io.Discard)
+	statuses := bufferStatuses(context)
+
+	api, err := pp.StartApi("127.0.0.1:0", []*pp.ExecutionContext{context})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer api.Close()
+	url := "http://" + api.Address() + "/processes/repl"
+
+	getProcess := func() pp.ProcessInfo {
+		info := pp.ProcessInfo{}
+		response, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer response.Body.Close()
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&info))
+		return info
+	}
+
+	context.Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start")
+	info := getProcess()
+	assert.True(t, info.Running)
+	assert.Greater(t, info.Pid, 0, "Running processes should report their PID")
+
+	response, err := http.Post(url+"/stdin", "application/json", strings.NewReader(`{"input": "GET http://localhost:8080/\n"}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	// Wait for the process to echo the input
+	echoed := false
+	for range 20 {
+		for _, line := range context.RecentLogs(0) {
+			echoed = echoed || line.Text == "GET http://localhost:8080/"
+		}
+		if echoed {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.True(t, echoed, "Input should be written to the process")
+
+	response, err = http.Post(url+"/restart", "application/json", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, 2*time.Second), "Process should run after restarting")
+	info = getProcess()
+	assert.Equal(t, 1, info.Restarts)
+	if assert.NotNil(t, info.ExitCode) {
+		assert.Equal(t, -1, *info.ExitCode, "Restarted processes are stopped by a signal")
+	}
+
+	assert.NoError(t, context.CloseInput())
+	wg.Wait()
+	info = getProcess()
+	assert.False(t, info.Running)
+	assert.Equal(t, 0, *info.ExitCode)
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that restart delays grow and repeated failures are reported as crash looping
+func TestRestartBackoff(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateFailCmdSettings()
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 5, 0)
+	process.Backoff = pp.Backoff{Initial: 50, Multiplier: 2}
+	context := process.CreateContext(&wg)
+
+	statusChannel := context.GetProcessNotificationChannel()
+	observed := make(chan struct{})
+	var statuses []pp.ProcessStatus
+	go func() {
+		defer close(observed)
+		for status := range statusChannel {
+			statuses = append(statuses, status)
+		}
+	}()
+
+	t1 := time.Now()
+	context.Start()
+	wg.Wait()
+	<-observed
+
+	// 50 + 100 + 200 + 400 ms between the five runs
+	assert.GreaterOrEqual(t, time.Since(t1), 750*time.Millisecond, "Restart delays should grow")
+	assert.Less(t, time.Since(t1), 3*time.Second, "Restart delays should not exceed the backoff")
+	assert.Contains(t, statuses, pp.ProcessStatusRestarting, "First failures should restart")
+	assert.Contains(t, statuses, pp.ProcessStatusCrashLooping, "Repeated failures should be crash looping")
+	assert.Equal(t, pp.ProcessStatusExited, context.Status, "Final status should be exited")
+}
+
+// Ensure that runs lasting the restart window reset the restart attempts
+func TestRestartWindow(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(1)
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 2, 0)
+	process.RestartWindow = 1
+	context := process.CreateContext(&wg)
+
+	statusChannel := context.GetProcessNotificationChannel()
+	runs := make(chan struct{}, 10)
+	observed := make(chan struct{})
+	go func() {
+		defer close(observed)
+		for status := range statusChannel {
+			if status == pp.ProcessStatusRunning {
+				runs <- struct{}{}
+			}
+		}
+	}()
+
+	context.Start()
+	// Two restart attempts only allow two runs without the window resetting them
+	for range 3 {
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Process should keep restarting after stable runs")
+		}
+	}
+	context.BuzzkillProcess()
+	wg.Wait()
+	<-observed
+	assert.Equal(t, pp.ProcessStatusExited, context.Status, "Final status should be exited")
+}
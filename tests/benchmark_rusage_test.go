@@ -0,0 +1,64 @@
+//go:build linux || darwin
+
+package tests
+
+import (
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+)
+
+// Returns the CPU time used by the test process so far
+func cpuTime(b *testing.B) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		b.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// Measures the CPU used by process party while 30 processes are running and idle
+func BenchmarkIdleCPU(b *testing.B) {
+	var wg sync.WaitGroup
+	numberOfProcesses := 30
+	idleTime := time.Duration(100) * time.Millisecond
+
+	contexts := []*pp.ExecutionContext{}
+	for i := range numberOfProcesses {
+		cmdSettings := testHelpers.CreateSleepCmdSettings(60)
+		process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+		process.Name = "idle" + strconv.Itoa(i)
+		contexts = append(contexts, process.CreateContext(&wg))
+	}
+	for _, context := range contexts {
+		statusChannel := context.GetProcessNotificationChannel()
+		context.Start()
+		for status := range statusChannel {
+			if status == pp.ProcessStatusRunning {
+				break
+			}
+		}
+		// Keep the channel drained so the context never blocks on it
+		go func() {
+			for range statusChannel {
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	start := cpuTime(b)
+	for range b.N {
+		time.Sleep(idleTime)
+	}
+	used := cpuTime(b) - start
+	b.StopTimer()
+	b.ReportMetric(float64(used)/float64(time.Duration(b.N)*idleTime)*100, "%cpu")
+
+	pp.Shutdown(contexts)
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+)
+
+// Measures the time it takes to detect a process exiting and start it again
+func BenchmarkRestartLatency(b *testing.B) {
+	var wg sync.WaitGroup
+	cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, b.N+1, 0)
+	process.OnComplete = pp.ExitCommandRestart
+	context := process.CreateContext(&wg)
+
+	b.ResetTimer()
+	context.Start()
+	wg.Wait()
+}
+
+// Measures the time it takes for a trigger to stop the running process and start it again
+func BenchmarkTriggerRestartLatency(b *testing.B) {
+	var wg sync.WaitGroup
+	cmdSettings := testHelpers.CreateSleepCmdSettings(30)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.Trigger.RunOnStart = true
+	process.Trigger.EndOnNew = true
+	process.Trigger.Process.OnStart = []string{"source"}
+	process.StopTimeout = 1
+
+	sourceSettings := testHelpers.CreateSleepCmdSettings(0)
+	source := createWaitProcess(sourceSettings.Cmd, sourceSettings.Args, 0)
+	source.Name = "source"
+	source.OnComplete = pp.ExitCommandRestart
+	source.RestartAttempts = b.N
+
+	contexts := []*pp.ExecutionContext{process.CreateContext(&wg), source.CreateContext(&wg)}
+	if err := pp.LinkProcessTriggers(contexts); err != nil {
+		b.Fatal(err)
+	}
+	statusChannel := contexts[0].GetProcessNotificationChannel()
+	runs := make(chan struct{}, b.N+2)
+	go func() {
+		for status := range statusChannel {
+			if status == pp.ProcessStatusRunning {
+				runs <- struct{}{}
+			}
+		}
+	}()
+
+	contexts[0].Start()
+	<-runs
+	b.ResetTimer()
+	contexts[1].Start()
+	for range b.N {
+		<-runs
+	}
+	b.StopTimer()
+	<-contexts[1].Done()
+	contexts[0].BuzzkillProcess()
+	wg.Wait()
+}
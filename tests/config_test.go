@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	pp "github.com/mpmcintyre/process-party/internal"
+	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +23,8 @@ var tpDelays int = 1
 var tpPID string = "123"
 var tpRestartAttempts int = 1
 var startStream string = "start"
+var tpShell bool = true
+var tpDebounceTime uint16 = 50
 
 // Creates a process with non-default values
 func createRunTask(increment int, nameStamp string) pp.Process {
@@ -27,6 +33,7 @@ func createRunTask(increment int, nameStamp string) pp.Process {
 		OnComplete:      tpExit,
 		RestartAttempts: tpRestartAttempts,
 		RestartDelay:    tpDelays,
+		RestartWindow:   tpDelays,
 		Delay:           tpDelays,
 		Name:            nameStamp + fmt.Sprintf("%d", increment),
 		Command:         nameStamp + fmt.Sprintf("%d", increment),
@@ -37,22 +44,64 @@ func createRunTask(increment int, nameStamp string) pp.Process {
 		StartStream:     startStream,
 		Pid:             tpPID,
 		Silent:          true,
+		StopSignal:      "SIGTERM",
+		StopTimeout:     tpDelays,
+		Cwd:             nameStamp,
+		Shell:           &tpShell,
+		Env:             map[string]string{"TEST": nameStamp},
+		EnvFiles:        []string{nameStamp + ".env"},
+		LogFile: pp.LogFile{
+			Path:        nameStamp + ".log",
+			Prefix:      true,
+			Colour:      true,
+			MaxSize:     tpDelays,
+			RotateHours: tpDelays,
+			MaxFiles:    tpRestartAttempts,
+			MaxAgeDays:  tpDelays,
+		},
+		Backoff: pp.Backoff{
+			Initial:    tpDelays,
+			Multiplier: 2,
+			Max:        tpDelays,
+			Jitter:     0.5,
+		},
+		SuccessExitCodes:    []int{2},
+		RestartOnExitCodes:  []int{3},
+		BuzzkillOnExitCodes: []int{130},
+		DependsOn: []pp.Dependency{
+			{Process: "test", Condition: pp.DependencyHealthy},
+		},
+		HealthCheck: pp.HealthCheck{
+			Http:               "http://localhost:8080/" + nameStamp,
+			Tcp:                "localhost:8080",
+			Exec:               nameStamp,
+			LogPattern:         nameStamp,
+			Interval:           tpDelays,
+			Timeout:            tpDelays,
+			Retries:            tpRestartAttempts,
+			StartPeriod:        tpDelays,
+			RestartOnUnhealthy: true,
+		},
 		// These must be set by the config file not the process
 		ShowTimestamp: false,
 		Trigger: pp.Trigger{
 			RunOnStart: true,
 			EndOnNew:   true,
 			FileSystem: pp.FileSystemTrigger{
-				DebounceTime:   50,
+				DebounceTime:   &tpDebounceTime,
+				MaxWait:        500,
 				NonRecursive:   true,
 				Watch:          []string{"test"},
 				Ignore:         []string{"test"},
 				ContainFilters: []string{"test"},
+				Gitignore:      true,
+				Events:         []string{"write", "chmod"},
 			},
 			Process: pp.ProcessTrigger{
 				OnStart:    []string{"test"},
 				OnComplete: []string{"test"},
 				OnError:    []string{"test"},
+				AllowLoop:  true,
 			},
 		},
 	}
@@ -182,6 +231,9 @@ func createNonDefaultConfig(numberOfProcesses int, nameStamp string, tempDir str
 
 	// Set the global settings in the config to non default values
 	config.ShowTimestamp = true
+	config.Success = pp.SuccessLast
+	config.Api = "127.0.0.1:7070"
+	config.LogFile = pp.LogFile{Path: "logs/{name}.log", MaxFiles: 3}
 
 	jString, err := json.Marshal(config)
 	if err != nil {
@@ -242,6 +294,17 @@ func TestConfigParsing(t *testing.T) {
 	if !jsonConfig.ShowTimestamp {
 		t.Fatalf("config contains default value")
 	}
+	for _, config := range []*pp.Config{jsonConfig, ymlConfig, yamlConfig, tomlConfig} {
+		if config.Success != pp.SuccessLast {
+			t.Fatalf("config contains default value")
+		}
+		if config.Api != "127.0.0.1:7070" {
+			t.Fatalf("config contains default value")
+		}
+		if config.LogFile.MaxFiles != 3 || !strings.HasSuffix(config.LogFile.Path, filepath.Join("logs", "{name}.log")) {
+			t.Fatalf("config contains default value")
+		}
+	}
 
 	for index := range numberOfTestProcesses {
 		if containsDefaultValues(jsonConfig.Processes[index]) {
@@ -277,3 +340,130 @@ func TestConfigParsing(t *testing.T) {
 
 	})
 }
+
+// Ensure that relative paths in the config resolve against the config file directory
+func TestConfigPathResolution(t *testing.T) {
+	t.Parallel()
+	tempDir := filepath.Join(".tmp", "config-paths")
+	os.RemoveAll(tempDir)
+	err := os.MkdirAll(tempDir, 0755)
+	assert.Nil(t, err, "Could not create the temp folder")
+
+	configText := `
+env_file = ["global.env"]
+
+[[processes]]
+name = "relative"
+command = "ls"
+cwd = "frontend"
+env_file = ["frontend.env", "/absolute.env"]
+
+[[processes]]
+name = "absolute"
+command = "ls"
+cwd = "/"
+
+[[processes]]
+name = "default"
+command = "ls"
+`
+	err = os.WriteFile(filepath.Join(tempDir, "process-party.toml"), []byte(configText), 0644)
+	assert.Nil(t, err)
+
+	config := pp.CreateConfig()
+	// Parse the directory to ensure the discovered file is used as the origin
+	err = config.ParseFile(tempDir, true)
+	assert.Nil(t, err)
+
+	absDir, err := filepath.Abs(tempDir)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{filepath.Join(absDir, "global.env")}, config.EnvFiles)
+	assert.Equal(t, filepath.Join(absDir, "frontend"), config.Processes[0].Cwd)
+	assert.Equal(t, []string{filepath.Join(absDir, "frontend.env"), "/absolute.env"}, config.Processes[0].EnvFiles)
+	assert.Equal(t, []string{filepath.Join(absDir, "global.env")}, config.Processes[0].GlobalEnvFiles)
+	assert.Equal(t, "/", config.Processes[1].Cwd)
+	assert.Equal(t, "", config.Processes[2].Cwd, "No cwd should run in the current directory")
+
+	t.Cleanup(func() {
+		os.RemoveAll(tempDir)
+	})
+}
+
+// Ensure that fs triggers without a debounce time use the default, while an explicit 0 fires on every change
+func TestConfigDebounceDefault(t *testing.T) {
+	t.Parallel()
+	tempDir := filepath.Join(".tmp", "config-debounce")
+	os.RemoveAll(tempDir)
+	err := os.MkdirAll(tempDir, 0755)
+	assert.Nil(t, err, "Could not create the temp folder")
+
+	configText := `
+[[processes]]
+name = "default"
+command = "ls"
+trigger.filesystem.watch = ["src"]
+
+[[processes]]
+name = "configured"
+command = "ls"
+trigger.filesystem.watch = ["src"]
+trigger.filesystem.debounce_ms = 200
+
+[[processes]]
+name = "immediate"
+command = "ls"
+trigger.filesystem.watch = ["src"]
+trigger.filesystem.debounce_ms = 0
+`
+	err = os.WriteFile(filepath.Join(tempDir, "process-party.toml"), []byte(configText), 0644)
+	assert.Nil(t, err)
+
+	config := pp.CreateConfig()
+	err = config.ParseFile(tempDir, true)
+	assert.Nil(t, err)
+	assert.Equal(t, pp.DefaultDebounceTime*time.Millisecond, config.Processes[0].Trigger.FileSystem.GetDebounceTime())
+	assert.Equal(t, 200*time.Millisecond, config.Processes[1].Trigger.FileSystem.GetDebounceTime())
+	assert.Equal(t, time.Duration(0), config.Processes[2].Trigger.FileSystem.GetDebounceTime(), "An explicit 0 should be kept")
+
+	t.Cleanup(func() {
+		os.RemoveAll(tempDir)
+	})
+}
+
+// Ensure that inline commands are split into a command and arguments
+func TestParseInlineCmd(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		shell   bool
+		input   string
+		command string
+		args    []string
+		errors  bool
+	}{
+		{"single command", false, "ls", "ls", []string{}, false},
+		{"arguments", false, "go run main.go", "go", []string{"run", "main.go"}, false},
+		{"quoted arguments", false, `echo "hello world" 'a b'`, "echo", []string{"hello world", "a b"}, false},
+		{"unterminated quote", false, `echo "hello`, "", nil, true},
+		{"empty", false, "", "", nil, true},
+		{"shell keeps the command", true, "npm run build && npm start | tee log", "npm run build && npm start | tee log", []string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := pp.CreateConfig()
+			config.Shell = tt.shell
+			err := config.ParseInlineCmd(tt.input)
+			if tt.errors {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.command, config.Processes[0].Command)
+			assert.Equal(t, tt.args, config.Processes[0].Args)
+			assert.Equal(t, tt.shell, config.Processes[0].UseShell())
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Forwards the statuses of the context into a channel that is large enough to never block the context
+func bufferStatuses(context *pp.ExecutionContext) chan pp.ProcessStatus {
+	statusChannel := context.GetProcessNotificationChannel()
+	statuses := make(chan pp.ProcessStatus, 100)
+	go func() {
+		defer close(statuses)
+		for status := range statusChannel {
+			statuses <- status
+		}
+	}()
+	return statuses
+}
+
+// Ensure that processes can be stopped, started, killed and restarted without ending the context
+func TestControlStopStart(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(30)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.StopTimeout = 1
+	context := process.CreateContext(&wg)
+	statuses := bufferStatuses(context)
+
+	assert.ErrorIs(t, context.StartProcess(), pp.ErrProcessNotStarted)
+	context.Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start")
+	assert.ErrorIs(t, context.StartProcess(), pp.ErrProcessRunning)
+	assert.ErrorIs(t, context.TriggerProcess(), pp.ErrNoTriggers)
+
+	assert.NoError(t, context.StopProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusStopped, 2*time.Second), "Process should stop")
+	assert.ErrorIs(t, context.StopProcess(), pp.ErrProcessNotRunning)
+	select {
+	case <-context.Done():
+		t.Fatal("Stopped processes should not end the context")
+	default:
+	}
+
+	assert.NoError(t, context.StartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start again")
+
+	assert.NoError(t, context.RestartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRestarting, 2*time.Second), "Process should restart")
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should run after restarting")
+
+	t1 := time.Now()
+	assert.NoError(t, context.KillProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusStopped, 2*time.Second), "Process should be killed")
+	assert.Less(t, time.Since(t1), time.Second, "Killing should not wait for the process to stop")
+
+	assert.NoError(t, context.RestartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Restarting a stopped process should start it")
+
+	context.BuzzkillProcess()
+	wg.Wait()
+	assert.ErrorIs(t, context.StartProcess(), pp.ErrProcessEnded)
+}
+
+// Ensure that processes that ended can be started again while the party is running
+func TestControlStartEnded(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	failSettings := testHelpers.CreateFailCmdSettings()
+	sleepSettings := testHelpers.CreateSleepCmdSettings(30)
+	config := pp.CreateConfig()
+	config.Processes = []pp.Process{
+		createWaitProcess(failSettings.Cmd, failSettings.Args, 0),
+		createWaitProcess(sleepSettings.Cmd, sleepSettings.Args, 0),
+	}
+	config.Processes[1].Name = "sleep"
+	config.Processes[1].StopTimeout = 1
+	contexts := config.GenerateRunTaskContexts(&wg)
+	statuses := bufferStatuses(contexts[0])
+
+	for _, context := range contexts {
+		context.Start()
+	}
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusFailed, time.Second), "Process should fail")
+	assert.NoError(t, contexts[0].StartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Ended process should start again")
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusFailed, time.Second), "Process should fail again")
+	assert.NoError(t, contexts[0].RestartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Ended process should restart")
+	select {
+	case <-contexts[0].Done():
+		t.Fatal("Ended processes should not end the context while the party is running")
+	default:
+	}
+
+	pp.Shutdown(contexts)
+	wg.Wait()
+	assert.ErrorIs(t, contexts[0].StartProcess(), pp.ErrProcessEnded)
+}
+
+// Ensure that the party ends once every process has ended
+func TestControlPartyEnds(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+	config := pp.CreateConfig()
+	config.Processes = []pp.Process{
+		createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0),
+		createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0),
+	}
+	config.Processes[1].Name = "second"
+	contexts := config.GenerateRunTaskContexts(&wg)
+
+	for _, context := range contexts {
+		context.Start()
+	}
+	ended := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Party should end once every process has ended")
+	}
+}
+
+// Ensure that stopping a process during its restart delay stops it from restarting
+func TestControlStopRestartDelay(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateFailCmdSettings()
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, -1, 30)
+	context := process.CreateContext(&wg)
+	statuses := bufferStatuses(context)
+
+	context.Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRestarting, time.Second), "Process should wait to restart")
+	t1 := time.Now()
+	assert.NoError(t, context.StopProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusStopped, time.Second), "Process should stop")
+	assert.Less(t, time.Since(t1), time.Second, "Stopping should not wait for the restart delay")
+
+	context.BuzzkillProcess()
+	wg.Wait()
+}
+
+// Ensure that processes with triggers can be triggered manually, and ignore triggers while stopped
+func TestControlTrigger(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(30)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.StopTimeout = 1
+	process.Trigger.EndOnNew = true
+	process.Trigger.Process.OnComplete = []string{"source"}
+	source := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	source.Name = "source"
+
+	// The source is never started, so only manual triggers start the process
+	contexts := []*pp.ExecutionContext{process.CreateContext(&wg), source.CreateContext(&wg)}
+	assert.NoError(t, pp.LinkProcessTriggers(contexts))
+	statuses := bufferStatuses(contexts[0])
+
+	contexts[0].Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusWaitingTrigger, time.Second), "Process should wait for a trigger")
+	assert.ErrorIs(t, contexts[0].StopProcess(), pp.ErrProcessNotRunning)
+
+	assert.NoError(t, contexts[0].TriggerProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should run when triggered")
+	assert.NoError(t, contexts[0].TriggerProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusWaitingTrigger, 2*time.Second), "Triggering should end the running process")
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Triggering should start a new execution")
+
+	assert.NoError(t, contexts[0].StopProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusStopped, 2*time.Second), "Process should stop")
+	assert.NoError(t, contexts[0].StartProcess())
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Stopped processes with triggers should start")
+
+	pp.Shutdown(contexts)
+	wg.Wait()
+}
+
+// Ensure that signals are sent to the running process
+func TestControlSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Stop signals are not supported on windows")
+	}
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateTrapCmdSettings(10)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	context := process.CreateContext(&wg)
+	statuses := bufferStatuses(context)
+
+	assert.ErrorIs(t, context.Signal(syscall.SIGTERM), pp.ErrProcessNotRunning)
+	context.Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start")
+	// Give the process time to set up its signal handlers
+	time.Sleep(200 * time.Millisecond)
+
+	t1 := time.Now()
+	assert.NoError(t, context.Signal(syscall.SIGTERM))
+	wg.Wait()
+	assert.Less(t, time.Since(t1), 2*time.Second, "Process should exit on the signal")
+	assert.Equal(t, 0, context.ExitCode, "Process should handle the signal")
+	assert.Equal(t, pp.ProcessStatusExited, context.Status)
+}
+
+// Ensure that closing the input of a process sends it an end of file
+func TestCloseInput(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateStdinCmdSettings(10)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	context := process.CreateContext(&wg)
+	statuses := bufferStatuses(context)
+
+	assert.ErrorIs(t, context.CloseInput(), pp.ErrProcessNotRunning)
+	context.Start()
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start")
+	assert.NoError(t, context.Write("GET http://localhost:8080/"))
+
+	t1 := time.Now()
+	assert.NoError(t, context.CloseInput())
+	wg.Wait()
+	assert.Less(t, time.Since(t1), 2*time.Second, "Process should exit once its input is closed")
+	assert.Equal(t, 0, context.ExitCode, "Process should see the end of its input")
+	for range 20 {
+		assert.ErrorIs(t, context.Write("GET http://localhost:8080/"), pp.ErrProcessNotRunning, "Writing after the context ended should not block or panic")
+	}
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Records when the context first reaches the status, the returned function waits for the context to end
+func recordStatusTime(context *pp.ExecutionContext, expected pp.ProcessStatus) func() time.Time {
+	var reached time.Time
+	observed := make(chan struct{})
+	statusChannel := context.GetProcessNotificationChannel()
+	go func() {
+		defer close(observed)
+		for status := range statusChannel {
+			if status == expected && reached.IsZero() {
+				reached = time.Now()
+			}
+		}
+	}()
+	return func() time.Time {
+		<-observed
+		return reached
+	}
+}
+
+// Ensure that invalid dependencies are rejected when linking
+func TestLinkDependencyErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		dependsOn  [][]pp.Dependency
+		healthy    bool
+		linkErrors string
+	}{
+		{"No dependencies", [][]pp.Dependency{{}, {}, {}}, false, ""},
+		{"Chain", [][]pp.Dependency{{}, {{Process: "dep0"}}, {{Process: "dep1", Condition: pp.DependencyCompletedSuccessfully}}}, false, ""},
+		{"Healthy with healthcheck", [][]pp.Dependency{{}, {{Process: "dep0", Condition: pp.DependencyHealthy}}, {}}, true, ""},
+		{"Healthy without healthcheck", [][]pp.Dependency{{}, {{Process: "dep0", Condition: pp.DependencyHealthy}}, {}}, false, "dep1 depends on dep0 being healthy but dep0 has no healthcheck"},
+		{"Unknown condition", [][]pp.Dependency{{}, {{Process: "dep0", Condition: "ready"}}, {}}, false, "unknown dependency condition \"ready\" on dep1 -- started, healthy or completed_successfully supported"},
+		{"Non-existent", [][]pp.Dependency{{{Process: "missing"}}, {}, {}}, false, "Specified dependency does not exist on dep0, Non existant dependency = missing"},
+		{"Self", [][]pp.Dependency{{{Process: "dep0"}}, {}, {}}, false, "Circular dependency detected: dep0 -> dep0"},
+		{"Mutual", [][]pp.Dependency{{{Process: "dep1"}}, {{Process: "dep0"}}, {}}, false, "Circular dependency detected: dep0 -> dep1 -> dep0"},
+		{"Full graph", [][]pp.Dependency{{{Process: "dep1"}}, {{Process: "dep2"}}, {{Process: "dep0"}}}, false, "Circular dependency detected: dep0 -> dep1 -> dep2 -> dep0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			contexts := []*pp.ExecutionContext{}
+			for i, dependsOn := range tt.dependsOn {
+				cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+				process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "dep"+strconv.Itoa(i))
+				process.DependsOn = dependsOn
+				if tt.healthy {
+					process.HealthCheck.LogPattern = "Sleeping"
+				}
+				contexts = append(contexts, process.CreateContext(&wg))
+			}
+
+			err := pp.LinkProcessDependencies(contexts)
+			if tt.linkErrors == "" {
+				assert.Nil(t, err, tt.name+" should not have errrored when linking")
+			} else {
+				assert.EqualError(t, err, tt.linkErrors)
+			}
+		})
+	}
+}
+
+// Ensure that processes only start once their dependencies reach the condition
+func TestDependencyConditions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		condition pp.DependencyCondition
+		fails     bool
+		starts    bool
+	}{
+		{"started", pp.DependencyStarted, false, true},
+		{"healthy", pp.DependencyHealthy, false, true},
+		{"completed successfully", pp.DependencyCompletedSuccessfully, false, true},
+		{"completed successfully after failure", pp.DependencyCompletedSuccessfully, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var wg sync.WaitGroup
+
+			cmdSettings := testHelpers.CreateSleepCmdSettings(1)
+			if tt.fails {
+				cmdSettings = testHelpers.CreateFailCmdSettings()
+			}
+			dependency := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "dependency")
+			dependency.Delay = 1
+			dependency.HealthCheck = pp.HealthCheck{LogPattern: "Sleeping", Interval: 50}
+
+			cmdSettings = testHelpers.CreateSleepCmdSettings(0)
+			dependent := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "dependent")
+			dependent.DependsOn = []pp.Dependency{{Process: "dependency", Condition: tt.condition}}
+
+			// The dependent is listed first to ensure the order comes from the dependencies
+			contexts := []*pp.ExecutionContext{dependent.CreateContext(&wg), dependency.CreateContext(&wg)}
+			err := pp.LinkProcessDependencies(contexts)
+			assert.Nil(t, err)
+
+			dependentStarted := recordStatusTime(contexts[0], pp.ProcessStatusRunning)
+			conditionStatus := map[pp.DependencyCondition]pp.ProcessStatus{
+				pp.DependencyStarted:               pp.ProcessStatusRunning,
+				pp.DependencyHealthy:               pp.ProcessStatusHealthy,
+				pp.DependencyCompletedSuccessfully: pp.ProcessStatusExited,
+			}
+			dependencyReady := recordStatusTime(contexts[1], conditionStatus[tt.condition])
+
+			for _, context := range contexts {
+				context.Start()
+			}
+			wg.Wait()
+
+			if !tt.starts {
+				assert.True(t, dependentStarted().IsZero(), "Dependent should not start")
+				assert.Equal(t, pp.ProcessStatusExited, contexts[0].Status)
+				return
+			}
+			assert.False(t, dependentStarted().IsZero(), "Dependent should start")
+			assert.False(t, dependencyReady().IsZero(), "Dependency should reach the condition")
+			assert.False(t, dependentStarted().Before(dependencyReady()), "Dependent should start after the dependency is %s", tt.condition)
+		})
+	}
+}
+
+// Ensure that processes waiting on dependencies stop when buzzkilled
+func TestDependencyBuzzkill(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(10)
+	dependency := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "dependency")
+	dependent := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "dependent")
+	dependent.DependsOn = []pp.Dependency{{Process: "dependency", Condition: pp.DependencyCompletedSuccessfully}}
+
+	contexts := []*pp.ExecutionContext{dependency.CreateContext(&wg), dependent.CreateContext(&wg)}
+	err := pp.LinkProcessDependencies(contexts)
+	assert.Nil(t, err)
+
+	statusChannel := contexts[1].GetProcessNotificationChannel()
+	for _, context := range contexts {
+		context.Start()
+	}
+	reached := waitForStatus(statusChannel, pp.ProcessStatusWaitingDependencies, time.Second)
+	assert.True(t, reached, "Dependent should wait for its dependencies")
+
+	t1 := time.Now()
+	pp.Shutdown(contexts)
+	wg.Wait()
+	assert.Less(t, time.Since(t1), time.Duration(5)*time.Second, "Shutdown should not wait for dependencies")
+	for _, context := range contexts {
+		assert.Equal(t, pp.ProcessStatusExited, context.Status)
+	}
+}
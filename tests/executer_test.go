@@ -1,6 +1,11 @@
 package tests
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -96,14 +101,17 @@ func TestInternalBuzzkill(t *testing.T) {
 	buzzkillTask.Silent = true
 
 	var buzzkilled atomic.Bool
+	observed := make(chan struct{})
 	bkChan := context.GetBuzkillEmitter()
 	go func() {
+		defer close(observed)
 		<-bkChan
 		buzzkilled.Store(true)
 	}()
 
 	context.Start()
 	wg.Wait()
+	<-observed
 	assert.True(t, buzzkilled.Load())
 }
 
@@ -166,7 +174,9 @@ func TestWait(t *testing.T) {
 	var waitingForTriggerRecieved atomic.Bool
 	var unknownRecieved atomic.Bool
 
+	observed := make(chan struct{})
 	go func() {
+		defer close(observed)
 		for {
 			value, ok := <-notificationChannel
 			if ok {
@@ -201,6 +211,8 @@ func TestWait(t *testing.T) {
 	t1 := time.Now()
 
 	wg.Wait()
+	// Wait for the remaining notifications to be handled
+	<-observed
 
 	// Status checks
 	assert.True(t, exitRecieved.Load(), "Should recieve exit status")
@@ -247,7 +259,9 @@ func TestRestart(t *testing.T) {
 	var unknownRecieved atomic.Bool
 	processRunCounter := 0
 
+	observed := make(chan struct{})
 	go func() {
+		defer close(observed)
 		for {
 			value, ok := <-notificationChannel
 			if ok {
@@ -281,6 +295,8 @@ func TestRestart(t *testing.T) {
 	context.Start()
 	t1 := time.Now()
 	wg.Wait()
+	// Wait for the remaining notifications to be handled
+	<-observed
 
 	assert.True(t, exitRecieved.Load(), "Should recieve exit status")
 	assert.False(t, failedRecieved.Load(), "Should not recieve failed status")
@@ -322,11 +338,14 @@ func TestRestartWithDelays(t *testing.T) {
 	var failedRecieved atomic.Bool
 	var startRecieved atomic.Bool
 	var restartRecieved atomic.Bool
+	var crashLoopRecieved atomic.Bool
 	var notStartedRecieved atomic.Bool
 	var waitingForTriggerRecieved atomic.Bool
 	var unknownRecieved atomic.Bool
 
+	observed := make(chan struct{})
 	go func() {
+		defer close(observed)
 		for {
 			value, ok := <-notificationChannel
 			if ok {
@@ -339,6 +358,8 @@ func TestRestartWithDelays(t *testing.T) {
 					startRecieved.Store(true)
 				case pp.ProcessStatusRestarting:
 					restartRecieved.Store(true)
+				case pp.ProcessStatusCrashLooping:
+					crashLoopRecieved.Store(true)
 				case pp.ProcessStatusNotStarted:
 					notStartedRecieved.Store(true)
 				case pp.ProcessStatusWaitingTrigger:
@@ -359,11 +380,14 @@ func TestRestartWithDelays(t *testing.T) {
 	context.Start()
 	t1 := time.Now()
 	wg.Wait()
+	// Wait for the remaining notifications to be handled
+	<-observed
 
 	assert.True(t, exitRecieved.Load(), "Should recieve exit status")
 	assert.True(t, failedRecieved.Load())
 	assert.True(t, startRecieved.Load(), "Should recieve running status")
 	assert.True(t, restartRecieved.Load(), "Should recieve restart status")
+	assert.True(t, crashLoopRecieved.Load(), "Should recieve crash looping status after repeated failures")
 	assert.True(t, notStartedRecieved.Load(), "Should recieve not started status (preparing)")
 	assert.False(t, waitingForTriggerRecieved.Load(), "Should not recieve waiting status")
 	assert.False(t, unknownRecieved.Load(), "Should not recieve unknown status")
@@ -406,7 +430,9 @@ func TestStartDelay(t *testing.T) {
 	var waitingForTriggerRecieved atomic.Bool
 	var unknownRecieved atomic.Bool
 
+	observed := make(chan struct{})
 	go func() {
+		defer close(observed)
 		for {
 			value, ok := <-notificationChannel
 			if ok {
@@ -438,6 +464,8 @@ func TestStartDelay(t *testing.T) {
 	context.Start()
 	t1 := time.Now()
 	wg.Wait()
+	// Wait for the remaining notifications to be handled
+	<-observed
 
 	assert.True(t, exitRecieved.Load(), "Should recieve exit status")
 	assert.False(t, failedRecieved.Load(), "Should not recieve failed status")
@@ -452,3 +480,270 @@ func TestStartDelay(t *testing.T) {
 	assert.False(t, buzzkilled, "Should not emit buzzkill during test")
 	assert.Equal(t, context.Status, pp.ProcessStatusExited)
 }
+
+// Ensure that the process runs inside its configured working directory
+func TestWorkingDirectory(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	tempDir := filepath.Join(".tmp", "cwd")
+	filename := "cwd.file"
+	os.RemoveAll(tempDir)
+	err := os.MkdirAll(tempDir, 0755)
+	assert.Nil(t, err, "Could not create the temp folder")
+
+	cmdSettings := testHelpers.CreateTouchCmdSettings(filename)
+	command, err := filepath.Abs(cmdSettings.Cmd)
+	assert.Nil(t, err)
+	waitTask := createWaitProcess(command, cmdSettings.Args, 0)
+	waitTask.Cwd, err = filepath.Abs(tempDir)
+	assert.Nil(t, err)
+
+	context := waitTask.CreateContext(
+		&wg,
+	)
+
+	context.Start()
+	wg.Wait()
+
+	fileFound, err := pp.FileExists(filename, tempDir)
+	assert.Nil(t, err)
+	assert.True(t, fileFound, "File should be created inside the working directory")
+	assert.Equal(t, pp.ProcessStatusExited, context.Status)
+
+	t.Cleanup(func() {
+		os.RemoveAll(tempDir)
+	})
+}
+
+// Ensure that shell mode runs the command through the shell
+func TestShellMode(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	tempDir := filepath.Join(".tmp", "shell")
+	os.RemoveAll(tempDir)
+	err := os.MkdirAll(tempDir, 0755)
+	assert.Nil(t, err, "Could not create the temp folder")
+
+	cmdSettings := testHelpers.CreateTouchCmdSettings("")
+	command, err := filepath.Abs(cmdSettings.Cmd)
+	assert.Nil(t, err)
+
+	shell := true
+	waitTask := createWaitProcess(`"`+command+`" touch first.file && "`+command+`" touch`, []string{"second file.file"}, 0)
+	waitTask.Shell = &shell
+	waitTask.Cwd, err = filepath.Abs(tempDir)
+	assert.Nil(t, err)
+
+	context := waitTask.CreateContext(
+		&wg,
+	)
+
+	context.Start()
+	wg.Wait()
+
+	for _, filename := range []string{"first.file", "second file.file"} {
+		fileFound, err := pp.FileExists(filename, tempDir)
+		assert.Nil(t, err)
+		assert.True(t, fileFound, "Shell should have created "+filename)
+	}
+	assert.Equal(t, pp.ProcessStatusExited, context.Status)
+
+	t.Cleanup(func() {
+		os.RemoveAll(tempDir)
+	})
+}
+
+// Ensure that processes ignoring the stop signal are killed after the stop timeout
+func TestStopTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Stop signals are not supported on windows")
+	}
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	sleepDuration := 10 // Seconds
+	stopTimeout := 1    // Seconds
+	cmdSettings := testHelpers.CreateIgnoreSignalsCmdSettings(sleepDuration)
+	waitTask := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	waitTask.StopTimeout = stopTimeout
+
+	context := waitTask.CreateContext(
+		&wg,
+	)
+
+	context.Start()
+	time.Sleep(time.Duration(200) * time.Millisecond)
+	t1 := time.Now()
+	context.BuzzkillProcess()
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, time.Since(t1), time.Duration(stopTimeout)*time.Second, "Should wait for the stop timeout")
+	assert.Less(t, time.Since(t1), time.Duration(sleepDuration)*time.Second, "Should kill the process after the stop timeout")
+}
+
+// Ensure that the configured stop signal is sent to the process
+func TestStopSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Stop signals are not supported on windows")
+	}
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	sleepDuration := 10 // Seconds
+	cmdSettings := testHelpers.CreateTrapCmdSettings(sleepDuration)
+	waitTask := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	waitTask.StopSignal = "SIGTERM"
+	waitTask.StopTimeout = sleepDuration
+
+	context := waitTask.CreateContext(
+		&wg,
+	)
+
+	context.Start()
+	time.Sleep(time.Duration(200) * time.Millisecond)
+	t1 := time.Now()
+	context.BuzzkillProcess()
+	wg.Wait()
+
+	assert.Less(t, time.Since(t1), time.Duration(2)*time.Second, "Process should exit on the stop signal")
+}
+
+// Ensure that shutdown stops every context in reverse order
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	sleepDuration := 10 // Seconds
+	numberOfProcesses := 3
+	contexts := []*pp.ExecutionContext{}
+	for i := range numberOfProcesses {
+		cmdSettings := testHelpers.CreateSleepCmdSettings(sleepDuration)
+		process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+		process.Name = "shutdown" + strconv.Itoa(i)
+		contexts = append(contexts, process.CreateContext(&wg))
+	}
+
+	for _, context := range contexts {
+		context.Start()
+	}
+	time.Sleep(time.Duration(200) * time.Millisecond)
+
+	var order []int
+	var orderMutex sync.Mutex
+	for i, context := range contexts {
+		go func() {
+			<-context.Done()
+			orderMutex.Lock()
+			order = append(order, i)
+			orderMutex.Unlock()
+		}()
+	}
+
+	t1 := time.Now()
+	pp.Shutdown(contexts)
+	wg.Wait()
+
+	assert.Less(t, time.Since(t1), time.Duration(sleepDuration)*time.Second, "Shutdown should stop the processes")
+	for _, context := range contexts {
+		assert.Equal(t, pp.ProcessStatusExited, context.Status)
+	}
+	// Allow the order goroutines to record the last context
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	orderMutex.Lock()
+	assert.Equal(t, []int{2, 1, 0}, order, "Should shut down in reverse order")
+	orderMutex.Unlock()
+}
+
+// Returns true if the process is running (zombies waiting to be reaped are not running)
+func processRunning(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is wrapped in brackets
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// Ensure that child processes are stopped with the process, or before restarting it, and outlive processes that exit on their own
+func TestProcessTreeKilled(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Process state checks require /proc")
+	}
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		sleepDuration int
+		buzzkill      bool
+		restart       bool
+		survives      bool
+	}{
+		{"buzzkill", 10, true, false, false},
+		{"restarted with children", 0, false, true, false},
+		{"exited with children", 0, false, false, true},
+	}
+
+	for index, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			tempDir := filepath.Join(".tmp", "process-tree"+strconv.Itoa(index))
+			os.RemoveAll(tempDir)
+			err := os.MkdirAll(tempDir, 0755)
+			assert.Nil(t, err, "Could not create the temp folder")
+			pidFile, err := filepath.Abs(filepath.Join(tempDir, "child.pid"))
+			assert.Nil(t, err)
+
+			cmdSettings := testHelpers.CreateSpawnCmdSettings(pidFile, tt.sleepDuration)
+			waitTask := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+			if tt.restart {
+				// Restart once, late enough to read the PID of the first child
+				waitTask = createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 2, 0)
+				waitTask.Backoff.Initial = 500
+			}
+			waitTask.StopTimeout = 1
+			context := waitTask.CreateContext(
+				&wg,
+			)
+
+			context.Start()
+			// Wait for the child to be spawned
+			pid := 0
+			for range 100 {
+				data, err := os.ReadFile(pidFile)
+				if err == nil && len(data) > 0 {
+					pid, _ = strconv.Atoi(string(data))
+					break
+				}
+				time.Sleep(time.Duration(20) * time.Millisecond)
+			}
+			assert.NotZero(t, pid, "Child process should have been spawned")
+
+			if tt.buzzkill {
+				assert.True(t, processRunning(pid), "Child process should be running")
+				context.BuzzkillProcess()
+			}
+			wg.Wait()
+
+			if tt.survives {
+				assert.True(t, processRunning(pid), "Child process should outlive a process that exited on its own")
+			} else {
+				assert.False(t, processRunning(pid), "Child process should not survive the process")
+			}
+
+			t.Cleanup(func() {
+				// Children of processes that exited on their own are left running
+				data, _ := os.ReadFile(pidFile)
+				lastPid, _ := strconv.Atoi(string(data))
+				for _, child := range []int{pid, lastPid} {
+					if process, err := os.FindProcess(child); err == nil && child != 0 {
+						process.Kill()
+					}
+				}
+				os.RemoveAll(tempDir)
+			})
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that exit code lists take precedence over on_failure and on_complete
+func TestExitCodeActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		exitCode    int
+		onFailure   pp.ExitCommand
+		success     []int
+		restart     []int
+		buzzkill    []int
+		runs        int
+		buzzkilled  bool
+		finalFailed bool
+	}{
+		{"failure without lists", 2, pp.ExitCommandWait, nil, nil, nil, 1, false, true},
+		{"success exit code", 2, pp.ExitCommandBuzzkill, []int{2}, nil, nil, 1, false, false},
+		{"restart exit code", 2, pp.ExitCommandWait, nil, []int{2}, nil, 3, false, true},
+		{"buzzkill exit code", 130, pp.ExitCommandWait, nil, nil, []int{130}, 1, true, true},
+		{"restart on success exit code", 2, pp.ExitCommandWait, []int{2}, []int{2}, nil, 3, false, false},
+		{"other exit code", 1, pp.ExitCommandWait, nil, []int{2}, []int{130}, 1, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var wg sync.WaitGroup
+
+			cmdSettings := testHelpers.CreateExitCmdSettings(tt.exitCode)
+			// Exit code restarts do not depend on the default restart attempts
+			process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0)
+			process.Backoff.Initial = 100
+			process.OnFailure = tt.onFailure
+			process.OnComplete = pp.ExitCommandWait
+			process.SuccessExitCodes = tt.success
+			process.RestartOnExitCodes = tt.restart
+			process.BuzzkillOnExitCodes = tt.buzzkill
+			context := process.CreateContext(&wg)
+
+			var runs, failures, restarts atomic.Int32
+			statusChannel := context.GetProcessNotificationChannel()
+			observed := make(chan struct{})
+			go func() {
+				defer close(observed)
+				for status := range statusChannel {
+					switch status {
+					case pp.ProcessStatusRunning:
+						runs.Add(1)
+					case pp.ProcessStatusFailed:
+						failures.Add(1)
+					case pp.ProcessStatusRestarting, pp.ProcessStatusCrashLooping:
+						// Exit code restarts never run out, stop once the expected runs are done
+						if restarts.Add(1) == int32(tt.runs) {
+							go context.BuzzkillProcess()
+						}
+					}
+				}
+			}()
+			var buzzkilled atomic.Bool
+			buzzkillObserved := make(chan struct{})
+			bkChan := context.GetBuzkillEmitter()
+			go func() {
+				defer close(buzzkillObserved)
+				value, ok := <-bkChan
+				buzzkilled.Store(ok && value)
+			}()
+
+			context.Start()
+			wg.Wait()
+			<-observed
+			<-buzzkillObserved
+
+			assert.Equal(t, int32(tt.runs), runs.Load(), "Should run the expected amount of times")
+			assert.Equal(t, tt.finalFailed, failures.Load() > 0, "Exit code should decide if the process failed")
+			assert.Equal(t, tt.buzzkilled, buzzkilled.Load())
+			assert.Equal(t, tt.exitCode, context.ExitCode)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the address of a port that is not accepting connections
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+// Waits for the context to reach the status, returns false on timeout
+func waitForStatus(statusChannel chan pp.ProcessStatus, expected pp.ProcessStatus, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case status, ok := <-statusChannel:
+			if !ok {
+				return false
+			}
+			if status == expected {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// Ensure that every type of health check moves the process into the expected state
+func TestHealthChecks(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	tests := []struct {
+		name        string
+		healthCheck pp.HealthCheck
+		expected    pp.ProcessStatus
+	}{
+		{"http healthy", pp.HealthCheck{Http: server.URL + "/ok"}, pp.ProcessStatusHealthy},
+		{"http unhealthy", pp.HealthCheck{Http: server.URL + "/fail", Retries: 2}, pp.ProcessStatusUnhealthy},
+		{"tcp healthy", pp.HealthCheck{Tcp: listener.Addr().String()}, pp.ProcessStatusHealthy},
+		{"tcp unhealthy", pp.HealthCheck{Tcp: closedAddress(t), Retries: 2}, pp.ProcessStatusUnhealthy},
+		{"exec healthy", pp.HealthCheck{Exec: "exit 0"}, pp.ProcessStatusHealthy},
+		{"exec unhealthy", pp.HealthCheck{Exec: "exit 1", Retries: 2}, pp.ProcessStatusUnhealthy},
+		{"log pattern healthy", pp.HealthCheck{LogPattern: "^Sleeping for \\d+"}, pp.ProcessStatusHealthy},
+		{"log pattern unhealthy", pp.HealthCheck{LogPattern: "never printed", Retries: 2}, pp.ProcessStatusUnhealthy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var wg sync.WaitGroup
+			cmdSettings := testHelpers.CreateSleepCmdSettings(10)
+			process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+			process.HealthCheck = tt.healthCheck
+			process.HealthCheck.Interval = 50
+			process.HealthCheck.Timeout = 500
+			context := process.CreateContext(&wg)
+			statusChannel := context.GetProcessNotificationChannel()
+
+			context.Start()
+			reached := waitForStatus(statusChannel, tt.expected, 5*time.Second)
+			assert.True(t, reached, "Process should become %v", tt.expected)
+			assert.True(t, context.Status.IsRunning(), "Process should still be running")
+
+			context.BuzzkillProcess()
+			wg.Wait()
+			assert.Equal(t, pp.ProcessStatusExited, context.Status)
+		})
+	}
+}
+
+// Ensure that failures during the start period are not counted
+func TestHealthCheckStartPeriod(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(10)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.HealthCheck = pp.HealthCheck{
+		Tcp:         closedAddress(t),
+		Interval:    50,
+		Retries:     1,
+		StartPeriod: 1000,
+	}
+	context := process.CreateContext(&wg)
+	statusChannel := context.GetProcessNotificationChannel()
+
+	t1 := time.Now()
+	context.Start()
+	reached := waitForStatus(statusChannel, pp.ProcessStatusUnhealthy, 5*time.Second)
+	assert.True(t, reached, "Process should become unhealthy")
+	assert.GreaterOrEqual(t, time.Since(t1), time.Second, "Failures in the start period should be ignored")
+
+	context.BuzzkillProcess()
+	wg.Wait()
+}
+
+// Ensure that unhealthy processes are restarted when configured
+func TestRestartOnUnhealthy(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(10)
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.HealthCheck = pp.HealthCheck{
+		Tcp:                closedAddress(t),
+		Interval:           50,
+		Retries:            2,
+		RestartOnUnhealthy: true,
+	}
+	process.StopTimeout = 1
+	context := process.CreateContext(&wg)
+	statusChannel := context.GetProcessNotificationChannel()
+
+	context.Start()
+	reached := waitForStatus(statusChannel, pp.ProcessStatusRestarting, 5*time.Second)
+	assert.True(t, reached, "Unhealthy process should restart")
+	reached = waitForStatus(statusChannel, pp.ProcessStatusRunning, 5*time.Second)
+	assert.True(t, reached, "Restarted process should be running")
+
+	context.BuzzkillProcess()
+	wg.Wait()
+	assert.Equal(t, pp.ProcessStatusExited, context.Status)
+}
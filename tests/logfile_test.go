@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that silent processes write their output to the global log file named after the process
+func TestLogFileSilentProcess(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	directory := t.TempDir()
+	cmdSettings := testHelpers.CreateFailCmdSettings()
+	process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	process.Name = "logged"
+	process.GlobalLogFile.Path = filepath.Join(directory, "{name}.log")
+	process.GlobalLogFile.Prefix = true
+	context := process.CreateContext(&wg)
+
+	context.Start()
+	wg.Wait()
+
+	content, err := os.ReadFile(filepath.Join(directory, "logged.log"))
+	assert.Nil(t, err)
+	assert.Regexp(t, `(?m)^\[wait-\d+\] failing task on purpouse$`, string(content))
+	assert.NotContains(t, string(content), "\x1b[")
+}
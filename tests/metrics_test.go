@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that the metrics count the restarts, exit codes and output of a failing process
+func TestMetricsRestarts(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateFailCmdSettings()
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 3, 0)
+	process.Name = "flaky"
+	process.Silent = false
+	context := process.CreateContext(&wg)
+	context.SetOutput(io.Discard, io.Discard)
+
+	context.Start()
+	wg.Wait()
+
+	metrics := &strings.Builder{}
+	pp.WriteMetrics(metrics, []*pp.ExecutionContext{context})
+	for _, line := range []string{
+		`process_party_process_status{process="flaky",status="exited"} 1`,
+		`process_party_process_restarts_total{process="flaky"} 2`,
+		`process_party_process_exits_total{process="flaky",code="1"} 3`,
+		`process_party_process_output_lines_total{process="flaky",stream="stdout"} 3`,
+		`process_party_process_uptime_seconds{process="flaky"} 0.000`,
+	} {
+		assert.Contains(t, metrics.String(), line+"\n")
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 )
 
@@ -32,9 +36,95 @@ func main() {
 			log.Fatal(err)
 		}
 
+	case "ignore-signals":
+		// Ignore stop signals so that the process has to be killed
+		signal.Ignore(os.Interrupt, syscall.SIGTERM)
+		i, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Ignoring signals for %d second(s)\n", i)
+		time.Sleep(time.Duration(i) * time.Second)
+
+	case "trap":
+		// Exit successfully on SIGTERM while ignoring SIGINT
+		signal.Ignore(os.Interrupt)
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGTERM)
+		i, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Waiting for SIGTERM for %d second(s)\n", i)
+		select {
+		case <-sigc:
+			fmt.Printf("Recieved SIGTERM\n")
+		case <-time.After(time.Duration(i) * time.Second):
+		}
+
+	case "spawn":
+		// Start a long running child process, write its PID to a file and sleep
+		child := exec.Command(os.Args[0], "sleep", "30")
+		err := child.Start()
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = os.WriteFile(args[1], []byte(strconv.Itoa(child.Process.Pid)), 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		i, err := strconv.Atoi(args[2])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Spawned child %d, sleeping for %d second(s)\n", child.Process.Pid, i)
+		time.Sleep(time.Duration(i) * time.Second)
+
+	case "stdin":
+		// Echo the standard input until it is closed, failing if it is not closed in time
+		i, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Reading input for %d second(s)\n", i)
+		closed := make(chan struct{})
+		go func() {
+			io.Copy(os.Stdout, os.Stdin)
+			close(closed)
+		}()
+		select {
+		case <-closed:
+			fmt.Printf("Input closed\n")
+		case <-time.After(time.Duration(i) * time.Second):
+			os.Exit(1)
+		}
+
+	case "partial":
+		// Write a line in two chunks followed by a blank line, and a prompt without a newline
+		fmt.Print("hel")
+		time.Sleep(50 * time.Millisecond)
+		fmt.Print("lo\n\nworld\nprompt> ")
+		time.Sleep(500 * time.Millisecond)
+		fmt.Println()
+
+	case "env":
+		// Print the given environment variables
+		for _, name := range args[1:] {
+			fmt.Printf("%s=%q\n", name, os.Getenv(name))
+		}
+
 	case "fail":
 		fmt.Printf("failing task on purpouse\n")
 		os.Exit(1)
+
+	case "exit":
+		// Exit with the given exit code
+		code, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Exiting with code %d\n", code)
+		os.Exit(code)
 	}
 
 	fmt.Printf("%s executed successfully\n", args[0])
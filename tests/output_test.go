@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Buffer shared by the output of the process and process party
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+// Ensure that the JSON output contains the output of the process and its lifecycle events
+func TestJsonOutput(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateFailCmdSettings()
+	process := createRestartProcess(cmdSettings.Cmd, cmdSettings.Args, 2, 0)
+	process.Name = "json"
+	process.Silent = false
+	context := process.CreateContext(&wg)
+	output := &lockedBuffer{}
+	context.SetJsonOutput(output)
+
+	context.Start()
+	wg.Wait()
+
+	events := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(output.buffer.String(), "\n"), "\n") {
+		decoded := pp.OutputLine{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded), line)
+		assert.Equal(t, "json", decoded.Process)
+		if decoded.Type == "event" {
+			events = append(events, decoded.Event)
+			if decoded.Event == pp.EventExited {
+				assert.Equal(t, 1, *decoded.ExitCode)
+			}
+		} else if decoded.Stream == "stdout" {
+			assert.Equal(t, "failing task on purpouse", decoded.Message)
+			assert.NotZero(t, decoded.Pid)
+		}
+	}
+	assert.Equal(t, []string{pp.EventStarted, pp.EventExited, pp.EventRestarting, pp.EventStarted, pp.EventExited}, events)
+}
+
+// Returns the events of the JSON output and the exit code of the last exited event
+func outputEvents(t *testing.T, output *lockedBuffer) ([]string, *int) {
+	t.Helper()
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	events := []string{}
+	var exitCode *int
+	for _, line := range strings.Split(strings.TrimSuffix(output.buffer.String(), "\n"), "\n") {
+		decoded := pp.OutputLine{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded), line)
+		if decoded.Type == "event" {
+			events = append(events, decoded.Event)
+		}
+		if decoded.Event == pp.EventExited {
+			exitCode = decoded.ExitCode
+		}
+	}
+	return events, exitCode
+}
+
+// Ensure that processes that fail to start or are stopped by a buzzkill report their exit
+func TestJsonOutputExitEvents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("start failure", func(t *testing.T) {
+		t.Parallel()
+		var wg sync.WaitGroup
+		process := createWaitProcess("process-party-command-that-does-not-exist", []string{}, 0)
+		process.Silent = false
+		context := process.CreateContext(&wg)
+		output := &lockedBuffer{}
+		context.SetJsonOutput(output)
+		context.Start()
+		wg.Wait()
+
+		events, exitCode := outputEvents(t, output)
+		assert.Equal(t, []string{pp.EventExited}, events)
+		if assert.NotNil(t, exitCode) {
+			assert.Equal(t, -1, *exitCode)
+		}
+	})
+
+	t.Run("buzzkill", func(t *testing.T) {
+		t.Parallel()
+		var wg sync.WaitGroup
+		cmdSettings := testHelpers.CreateSleepCmdSettings(5)
+		process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+		process.Silent = false
+		context := process.CreateContext(&wg)
+		output := &lockedBuffer{}
+		context.SetJsonOutput(output)
+		statuses := bufferStatuses(context)
+		context.Start()
+		assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, time.Second), "Process should start")
+		context.BuzzkillProcess()
+		wg.Wait()
+
+		events, exitCode := outputEvents(t, output)
+		assert.Equal(t, []string{pp.EventStarted, pp.EventExited}, events)
+		assert.NotNil(t, exitCode)
+	})
+}
+
+// Ensure that lines split between writes are assembled and kept apart from the output of other processes
+func TestJsonOutputPartialLines(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	output := &lockedBuffer{}
+	cmdSettings := testHelpers.CreatePartialCmdSettings()
+	for _, name := range []string{"first", "second"} {
+		process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+		process.Name = name
+		process.Silent = false
+		context := process.CreateContext(&wg)
+		context.SetJsonOutput(output)
+		context.Start()
+	}
+	wg.Wait()
+
+	messages := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSuffix(output.buffer.String(), "\n"), "\n") {
+		decoded := pp.OutputLine{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded), line)
+		if decoded.Stream == "stdout" {
+			messages[decoded.Process] = append(messages[decoded.Process], decoded.Message)
+		}
+	}
+	for _, name := range []string{"first", "second"} {
+		assert.Equal(t, []string{"hello", "", "world", "prompt> ", "partial executed successfully"}, messages[name])
+	}
+}
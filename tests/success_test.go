@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	pp "github.com/mpmcintyre/process-party/internal"
+	testHelpers "github.com/mpmcintyre/process-party/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensure that the success policy chooses the exit code of the right processes
+func TestSuccessPolicy(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateExitCmdSettings(3)
+	fast := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	fast.Name = "fast"
+	fast.Prefix = "f"
+
+	cmdSettings = testHelpers.CreateExitCmdSettings(0)
+	slow := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 1)
+	slow.Name = "slow"
+	slow.Prefix = "s"
+
+	never := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	never.Name = "never"
+	never.Prefix = "n"
+
+	contexts := []*pp.ExecutionContext{fast.CreateContext(&wg), slow.CreateContext(&wg), never.CreateContext(&wg)}
+	// The last process never runs
+	contexts[0].Start()
+	contexts[1].Start()
+	wg.Wait()
+
+	assert.Equal(t, 3, contexts[0].ExitCode)
+	assert.Equal(t, 0, contexts[1].ExitCode)
+
+	tests := []struct {
+		policy   pp.SuccessPolicy
+		invalid  bool
+		exitCode int
+	}{
+		{pp.SuccessAll, false, 3},
+		{pp.SuccessFirst, false, 3},
+		{pp.SuccessLast, false, 0},
+		{"command:slow", false, 0},
+		{"command:fast", false, 3},
+		{"command:s", false, 0},
+		{"command:0", false, 3},
+		{"command:never", false, 1},
+		{"command:missing", true, 0},
+		{"command:", true, 0},
+		{"most", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			err := tt.policy.Validate(contexts)
+			if tt.invalid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.exitCode, tt.policy.ExitCode(contexts))
+		})
+	}
+
+	t.Run("all successful", func(t *testing.T) {
+		assert.Equal(t, 0, pp.SuccessAll.ExitCode(contexts[1:]))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		config := pp.CreateConfig()
+		assert.Equal(t, pp.SuccessAll, config.GetSuccessPolicy())
+	})
+}
+
+// Ensure that every policy treats the success exit codes of the chosen process as success
+func TestSuccessPolicyExitCodes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		successExitCodes []int
+		exitCode         int
+	}{
+		{[]int{130}, 0},
+		{[]int{}, 130},
+	}
+	for _, tt := range tests {
+		var wg sync.WaitGroup
+		cmdSettings := testHelpers.CreateExitCmdSettings(130)
+		process := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+		process.Name = "interrupted"
+		process.SuccessExitCodes = tt.successExitCodes
+		contexts := []*pp.ExecutionContext{process.CreateContext(&wg)}
+		contexts[0].Start()
+		wg.Wait()
+
+		for _, policy := range []pp.SuccessPolicy{pp.SuccessAll, pp.SuccessFirst, pp.SuccessLast, "command:interrupted"} {
+			assert.Equal(t, tt.exitCode, policy.ExitCode(contexts), "%s with success exit codes %v", policy, tt.successExitCodes)
+		}
+	}
+}
+
+// Ensure that processes stopped by a shutdown, e.g. ctrl+c, do not fail the success policy
+func TestSuccessPolicyShutdown(t *testing.T) {
+	t.Parallel()
+	var wg sync.WaitGroup
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(5)
+	sleeping := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	sleeping.Name = "sleeping"
+	cmdSettings = testHelpers.CreateExitCmdSettings(0)
+	done := createWaitProcess(cmdSettings.Cmd, cmdSettings.Args, 0)
+	done.Name = "done"
+
+	contexts := []*pp.ExecutionContext{sleeping.CreateContext(&wg), done.CreateContext(&wg)}
+	statuses := bufferStatuses(contexts[0])
+	for _, context := range contexts {
+		context.Start()
+	}
+	assert.True(t, waitForStatus(statuses, pp.ProcessStatusRunning, 2*time.Second), "Process should start")
+	pp.Shutdown(contexts)
+	wg.Wait()
+
+	assert.Equal(t, -1, contexts[0].ExitCode, "The sleeping process should be killed")
+	for _, policy := range []pp.SuccessPolicy{pp.SuccessAll, pp.SuccessFirst, pp.SuccessLast, "command:sleeping"} {
+		assert.Equal(t, 0, policy.ExitCode(contexts), string(policy))
+	}
+}
@@ -2,9 +2,12 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -189,6 +192,10 @@ func TestLinkErrors(t *testing.T) {
 		contexts[1].Process.Trigger.Process.OnComplete = []string{contexts[2].Process.Name}
 		contexts[2].Process.Trigger.Process.OnComplete = []string{contexts[0].Process.Name}
 		err = pp.LinkProcessTriggers(contexts)
+		assert.EqualError(t, err, "Circular trigger detected: linkTest0 -> linkTest2 -> linkTest1 -> linkTest0 - set allow_loop on a process in the loop to allow it")
+		// Intentional loops are allowed
+		contexts[1].Process.Trigger.Process.AllowLoop = true
+		err = pp.LinkProcessTriggers(contexts)
 		assert.Nil(t, err)
 	})
 
@@ -208,6 +215,10 @@ func TestLinkErrors(t *testing.T) {
 		contexts[1].Process.Trigger.Process.OnError = []string{contexts[2].Process.Name}
 		contexts[2].Process.Trigger.Process.OnError = []string{contexts[0].Process.Name}
 		err = pp.LinkProcessTriggers(contexts)
+		assert.EqualError(t, err, "Circular trigger detected: linkTest0 -> linkTest2 -> linkTest1 -> linkTest0 - set allow_loop on a process in the loop to allow it")
+		// Intentional loops are allowed
+		contexts[1].Process.Trigger.Process.AllowLoop = true
+		err = pp.LinkProcessTriggers(contexts)
 		assert.Nil(t, err)
 	})
 
@@ -227,9 +238,35 @@ func TestLinkErrors(t *testing.T) {
 		contexts[1].Process.Trigger.Process.OnStart = []string{contexts[2].Process.Name}
 		contexts[2].Process.Trigger.Process.OnStart = []string{contexts[0].Process.Name}
 		err = pp.LinkProcessTriggers(contexts)
+		assert.EqualError(t, err, "Circular trigger detected: linkTest0 -> linkTest2 -> linkTest1 -> linkTest0 - set allow_loop on a process in the loop to allow it")
+		// Intentional loops are allowed
+		contexts[1].Process.Trigger.Process.AllowLoop = true
+		err = pp.LinkProcessTriggers(contexts)
 		assert.Nil(t, err)
 	})
 
+	contexts = []*pp.ExecutionContext{}
+	for i := range numberOfProcesses {
+		cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+		process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "linkTest"+strconv.Itoa(i))
+		process.Silent = true
+		context := process.CreateContext(&wg)
+		contexts = append(contexts, context)
+	}
+
+	t.Run("Recursive Process Mixed", func(t *testing.T) {
+		// A loop through different trigger types with a branch that does not loop
+		contexts[1].Process.Trigger.Process.OnStart = []string{contexts[0].Process.Name}
+		contexts[2].Process.Trigger.Process.OnComplete = []string{contexts[1].Process.Name}
+		contexts[3].Process.Trigger.Process.OnError = []string{contexts[2].Process.Name}
+		contexts[4].Process.Trigger.Process.OnComplete = []string{contexts[0].Process.Name}
+		err := pp.LinkProcessTriggers(contexts)
+		assert.Nil(t, err)
+		contexts[0].Process.Trigger.Process.OnError = []string{contexts[3].Process.Name}
+		err = pp.LinkProcessTriggers(contexts)
+		assert.EqualError(t, err, "Circular trigger detected: linkTest0 -> linkTest1 -> linkTest2 -> linkTest3 -> linkTest0 - set allow_loop on a process in the loop to allow it")
+	})
+
 	t.Cleanup(func() {
 		os.RemoveAll(existingDirPath)
 	})
@@ -614,7 +651,8 @@ func TestDebounce(t *testing.T) {
 	cmdSettings := testHelpers.CreateSleepCmdSettings(runtimeSec)
 	process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "trigger")
 	process.Silent = true
-	process.Trigger.FileSystem.DebounceTime = debounceTime
+	debounce := uint16(debounceTime)
+	process.Trigger.FileSystem.DebounceTime = &debounce
 
 	var wg sync.WaitGroup
 	context := process.CreateContext(&wg)
@@ -694,9 +732,9 @@ func TestDebounce(t *testing.T) {
 		}
 	}()
 
-	// Wait for file creation to complete and additional runtime
+	// Wait for file creation to complete, the trigger fires once no file changed for the debounce time
 	<-fileCreationDone
-	time.Sleep(time.Duration(runtimeSec) * time.Second)
+	time.Sleep(time.Duration(runtimeSec)*time.Second + 2*debounceTime*time.Millisecond)
 
 	// Cleanup and verify
 	context.BuzzkillProcess()
@@ -726,10 +764,11 @@ func TestTerminationFSOnTrigger(t *testing.T) {
 	cmdSettings := testHelpers.CreateSleepCmdSettings(runtimeSec)
 	process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "trigger")
 	process.Silent = true
-	process.Trigger.FileSystem.DebounceTime = 0
+	noDebounce := uint16(0)
+	process.Trigger.FileSystem.DebounceTime = &noDebounce
 	process.Trigger.EndOnNew = true
 
-	assert.Less(t, triggerIntervals*createdFiles/1000, runtimeSec+int(process.Trigger.FileSystem.DebounceTime), "The intervals across all triggers cannot be longer that the total runtime")
+	assert.Less(t, triggerIntervals*createdFiles/1000, runtimeSec+int(*process.Trigger.FileSystem.DebounceTime), "The intervals across all triggers cannot be longer that the total runtime")
 	var wg sync.WaitGroup
 	context := process.CreateContext(&wg)
 	context.Process.Trigger.FileSystem.Watch = []string{tempDir}
@@ -838,3 +877,271 @@ func TestTerminationFSOnTrigger(t *testing.T) {
 		}
 	})
 }
+
+// Ensure that ignore patterns, filters and ignore files decide which file changes trigger the process
+func TestFsTriggersPatterns(t *testing.T) {
+	t.Parallel()
+	// The files of the test are Go files, so they are created outside of the module
+	tempDir := t.TempDir()
+	for _, dir := range []string{"src/api/generated", "dist"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("dist/\n"), 0644))
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+	process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "patterns")
+	process.Silent = false
+	var wg sync.WaitGroup
+	context := process.CreateContext(&wg)
+	output := &lockedBuffer{}
+	context.SetJsonOutput(output)
+	context.Process.Trigger.FileSystem.Watch = []string{tempDir}
+	context.Process.Trigger.FileSystem.Ignore = []string{"src/**/generated/"}
+	context.Process.Trigger.FileSystem.ContainFilters = []string{"*.go"}
+	context.Process.Trigger.FileSystem.Gitignore = true
+	assert.Nil(t, pp.LinkProcessTriggers([]*pp.ExecutionContext{context}))
+	context.Start()
+
+	triggered := func() int {
+		output.mutex.Lock()
+		defer output.mutex.Unlock()
+		return strings.Count(output.buffer.String(), `"event":"triggered"`)
+	}
+	for _, file := range []string{"src/api/generated/api.go", "dist/main.go", "src/api/README.md"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(tempDir, file), []byte("ignored"), 0644))
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 0, triggered())
+
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "src", "api", "main.go"), []byte("package main"), 0644))
+	assert.Eventually(t, func() bool { return triggered() > 0 }, 2*time.Second, 20*time.Millisecond)
+
+	pp.Shutdown([]*pp.ExecutionContext{context})
+	wg.Wait()
+}
+
+// Returns the triggered events of the JSON output
+func triggeredEvents(t *testing.T, output *lockedBuffer) []pp.OutputLine {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+	events := []pp.OutputLine{}
+	for _, line := range strings.Split(strings.TrimSuffix(output.buffer.String(), "\n"), "\n") {
+		decoded := pp.OutputLine{}
+		if line == "" || !assert.Nil(t, json.Unmarshal([]byte(line), &decoded), line) {
+			continue
+		}
+		if decoded.Event == pp.EventTriggered {
+			events = append(events, decoded)
+		}
+	}
+	return events
+}
+
+// Creates a process watching the directory that writes its output as JSON
+func createFsJsonContext(t *testing.T, wg *sync.WaitGroup, tempDir string, debounceTime uint16, maxWait uint16) (*pp.ExecutionContext, *lockedBuffer) {
+	cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+	process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "debounce")
+	process.Silent = false
+	context := process.CreateContext(wg)
+	output := &lockedBuffer{}
+	context.SetJsonOutput(output)
+	context.Process.Trigger.FileSystem.Watch = []string{tempDir}
+	context.Process.Trigger.FileSystem.DebounceTime = &debounceTime
+	context.Process.Trigger.FileSystem.MaxWait = maxWait
+	assert.Nil(t, pp.LinkProcessTriggers([]*pp.ExecutionContext{context}))
+	return context, output
+}
+
+// Ensure that a burst of changes triggers a single run once the changes stopped, carrying every changed file
+func TestFsTriggerCoalescesChanges(t *testing.T) {
+	t.Parallel()
+	const createdFiles = 20
+	tempDir := t.TempDir()
+	var wg sync.WaitGroup
+	context, output := createFsJsonContext(t, &wg, tempDir, 150, 0)
+	context.Start()
+
+	for i := 0; i < createdFiles; i++ {
+		assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "file"+strconv.Itoa(i)), []byte("change"), 0644))
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Files changing again are only listed once
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "file0"), []byte("again"), 0644))
+	assert.Empty(t, triggeredEvents(t, output), "Should not trigger while files are changing")
+
+	assert.Eventually(t, func() bool { return len(triggeredEvents(t, output)) > 0 }, 2*time.Second, 20*time.Millisecond)
+	time.Sleep(300 * time.Millisecond)
+
+	events := triggeredEvents(t, output)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, pp.TriggerKindFs, events[0].Trigger)
+		assert.Contains(t, events[0].Message, strconv.Itoa(createdFiles)+" changes")
+		if assert.Len(t, events[0].Changes, createdFiles) {
+			assert.Equal(t, filepath.Join(tempDir, "file0"), events[0].Changes[0].Path)
+			assert.Contains(t, events[0].Changes[0].Op, "CREATE")
+			assert.Contains(t, events[0].Changes[0].Op, "WRITE")
+			assert.Equal(t, filepath.Join(tempDir, "file"+strconv.Itoa(createdFiles-1)), events[0].Changes[createdFiles-1].Path)
+		}
+	}
+
+	pp.Shutdown([]*pp.ExecutionContext{context})
+	wg.Wait()
+}
+
+// Ensure that the max wait triggers runs while files keep changing
+func TestFsTriggerMaxWait(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	var wg sync.WaitGroup
+	context, output := createFsJsonContext(t, &wg, tempDir, 200, 300)
+	context.Start()
+
+	path := filepath.Join(tempDir, "file")
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, os.WriteFile(path, []byte(strconv.Itoa(i)), 0644))
+		time.Sleep(50 * time.Millisecond)
+	}
+	events := triggeredEvents(t, output)
+	assert.GreaterOrEqual(t, len(events), 2, "Should trigger every max wait while the file keeps changing")
+	for _, event := range events {
+		if assert.Len(t, event.Changes, 1) {
+			assert.Equal(t, path, event.Changes[0].Path)
+		}
+	}
+
+	pp.Shutdown([]*pp.ExecutionContext{context})
+	wg.Wait()
+}
+
+// Ensure that the changed files and the kind of trigger are passed to the triggered process
+func TestFsTriggerChangedFiles(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	var wg sync.WaitGroup
+	cmdSettings := testHelpers.CreateEnvCmdSettings("PP_TRIGGER_SOURCE", "PP_CHANGED_FILES", "PP_CHANGED_EVENTS")
+	process := createBaseProcess(cmdSettings.Cmd, cmdSettings.Args, 0, 0, "changed")
+	process.Silent = false
+	context := process.CreateContext(&wg)
+	output := &lockedBuffer{}
+	context.SetJsonOutput(output)
+	context.Process.Trigger.FileSystem.Watch = []string{tempDir}
+	debounceTime := uint16(100)
+	context.Process.Trigger.FileSystem.DebounceTime = &debounceTime
+	assert.Nil(t, pp.LinkProcessTriggers([]*pp.ExecutionContext{context}))
+	context.Start()
+
+	first := filepath.Join(tempDir, "first.go")
+	second := filepath.Join(tempDir, "second.go")
+	assert.Nil(t, os.WriteFile(first, []byte("package first"), 0644))
+	assert.Nil(t, os.WriteFile(second, []byte("package second"), 0644))
+
+	messages := func() []string {
+		output.mutex.Lock()
+		defer output.mutex.Unlock()
+		messages := []string{}
+		for _, line := range strings.Split(strings.TrimSuffix(output.buffer.String(), "\n"), "\n") {
+			decoded := pp.OutputLine{}
+			if json.Unmarshal([]byte(line), &decoded) == nil && decoded.Stream == "stdout" {
+				messages = append(messages, decoded.Message)
+			}
+		}
+		return messages
+	}
+	assert.Eventually(t, func() bool { return len(messages()) >= 3 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{
+		`PP_TRIGGER_SOURCE="fs"`,
+		"PP_CHANGED_FILES=" + strconv.Quote(first+"\n"+second),
+		"PP_CHANGED_EVENTS=" + strconv.Quote("CREATE|WRITE "+first+"\n"+"CREATE|WRITE "+second),
+	}, messages()[:3])
+
+	pp.Shutdown([]*pp.ExecutionContext{context})
+	wg.Wait()
+}
+
+// Ensure that chmod does not trigger by default and atomic saves of a watched file trigger writes
+func TestFsTriggerEvents(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.toml")
+	assert.Nil(t, os.WriteFile(path, []byte("version = 1"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "other.toml"), []byte(""), 0644))
+
+	var wg sync.WaitGroup
+	context, output := createFsJsonContext(t, &wg, path, 50, 0)
+	context.Process.Trigger.FileSystem.Events = []string{"write"}
+	context.Start()
+
+	// Neither chmod nor other files in the directory of the watched file trigger the process
+	assert.Nil(t, os.Chmod(path, 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "other.toml"), []byte("changed"), 0644))
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, triggeredEvents(t, output))
+
+	// Save like vim, renaming the file to a backup before writing the new file. The second save ensures the file is still watched
+	for i := 1; i <= 2; i++ {
+		assert.Nil(t, os.Rename(path, path+"~"))
+		assert.Nil(t, os.WriteFile(path, []byte("version = "+strconv.Itoa(i+1)), 0644))
+		assert.Nil(t, os.Remove(path+"~"))
+		assert.Eventually(t, func() bool { return len(triggeredEvents(t, output)) == i }, 2*time.Second, 20*time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	events := triggeredEvents(t, output)
+	assert.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, []pp.FileChange{{Path: path, Op: "WRITE"}}, event.Changes)
+	}
+
+	pp.Shutdown([]*pp.ExecutionContext{context})
+	wg.Wait()
+}
+
+// Ensure that atomic saves are folded into a single write with the default debounce time of a parsed config
+func TestFsTriggerAtomicSaveDefaultDebounce(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.toml")
+	assert.Nil(t, os.WriteFile(path, []byte("version = 1"), 0644))
+
+	cmdSettings := testHelpers.CreateSleepCmdSettings(0)
+	command, err := filepath.Abs(cmdSettings.Cmd)
+	assert.Nil(t, err)
+	configText := fmt.Sprintf(`
+[[processes]]
+name = "atomic"
+command = %q
+args = ["sleep", "0"]
+trigger.filesystem.watch = [%q]
+trigger.filesystem.events = ["write"]
+`, command, path)
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "process-party.toml"), []byte(configText), 0644))
+	config := pp.CreateConfig()
+	assert.Nil(t, config.ParseFile(filepath.Join(tempDir, "process-party.toml"), true))
+	assert.Zero(t, config.Processes[0].Trigger.FileSystem.MaxWait)
+
+	var wg sync.WaitGroup
+	contexts := config.GenerateRunTaskContexts(&wg)
+	output := &lockedBuffer{}
+	contexts[0].SetJsonOutput(output)
+	assert.Nil(t, pp.LinkProcessTriggers(contexts))
+	contexts[0].Start()
+
+	// Save by moving the file away and a temporary file in its place. The file is only created again, so the save
+	// is only recognised as a write if both moves land within the debounce time
+	for i := 1; i <= 2; i++ {
+		assert.Nil(t, os.WriteFile(path+".tmp", []byte("version = "+strconv.Itoa(i+1)), 0644))
+		assert.Nil(t, os.Rename(path, path+"~"))
+		time.Sleep(10 * time.Millisecond)
+		assert.Nil(t, os.Rename(path+".tmp", path))
+		assert.Nil(t, os.Remove(path+"~"))
+		assert.Eventually(t, func() bool { return len(triggeredEvents(t, output)) == i }, 2*time.Second, 20*time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	events := triggeredEvents(t, output)
+	assert.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, []pp.FileChange{{Path: path, Op: "WRITE"}}, event.Changes)
+	}
+
+	pp.Shutdown(contexts)
+	wg.Wait()
+}